@@ -0,0 +1,19 @@
+package asql
+
+import "time"
+
+// Clock provides the current time. Application code that compares times with the database should depend on a
+// Clock rather than calling time.Now, so tests can freeze and move both together (see asqltest.Clock).
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+func (clock ClockFunc) Now() time.Time {
+	return clock()
+}
+
+// SystemClock returns the real time.
+var SystemClock Clock = ClockFunc(time.Now)
@@ -0,0 +1,19 @@
+package asql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/asql"
+)
+
+func TestClock(t *testing.T) {
+	date := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var clock asql.Clock = asql.ClockFunc(func() time.Time { return date })
+	require.Equal(t, date, clock.Now())
+
+	require.WithinDuration(t, time.Now(), asql.SystemClock.Now(), time.Second)
+}
@@ -0,0 +1,477 @@
+package asql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"github.com/a-novel-kit/quicklog"
+
+	asqlmessages "github.com/a-novel-kit/asql/messages"
+)
+
+// SchemaColumn describes a column of a table.
+type SchemaColumn struct {
+	Name string `json:"name"`
+	// Type is the formatted SQL type of the column, including modifiers (e.g. "character varying(255)").
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	// Default is the SQL expression of the default value. It is empty if the column has no default.
+	Default string `json:"default,omitempty"`
+}
+
+// SchemaIndex describes an index of a table. Primary keys and unique constraints are backed by indexes, so they
+// are listed here as well.
+type SchemaIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+	// Definition is the full CREATE INDEX statement.
+	Definition string `json:"definition"`
+}
+
+// SchemaForeignKey describes a foreign key constraint of a table.
+type SchemaForeignKey struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnUpdate          string   `json:"on_update"`
+	OnDelete          string   `json:"on_delete"`
+}
+
+// SchemaConstraint describes a primary key, unique, check or exclusion constraint of a table. Foreign keys are
+// described by SchemaForeignKey.
+type SchemaConstraint struct {
+	Name string `json:"name"`
+	// Type is one of "PRIMARY KEY", "UNIQUE", "CHECK" or "EXCLUDE".
+	Type string `json:"type"`
+	// Definition is the SQL definition of the constraint (e.g. "CHECK (price > 0)").
+	Definition string `json:"definition"`
+}
+
+// SchemaTrigger describes a trigger of a table.
+type SchemaTrigger struct {
+	Name string `json:"name"`
+	// Definition is the full CREATE TRIGGER statement.
+	Definition string `json:"definition"`
+}
+
+// SchemaTable describes the structure of a table.
+type SchemaTable struct {
+	Name        string             `json:"name"`
+	Columns     []SchemaColumn     `json:"columns"`
+	Indexes     []SchemaIndex      `json:"indexes"`
+	ForeignKeys []SchemaForeignKey `json:"foreign_keys"`
+	Constraints []SchemaConstraint `json:"constraints"`
+	Triggers    []SchemaTrigger    `json:"triggers"`
+}
+
+// SchemaEnum describes an enum type.
+type SchemaEnum struct {
+	Name string `json:"name"`
+	// Values of the enum, in their sort order.
+	Values []string `json:"values"`
+}
+
+// SchemaFunction describes a function or procedure. Functions that belong to an extension are ignored.
+type SchemaFunction struct {
+	Name string `json:"name"`
+	// Arguments identify the function among its overloads (e.g. "a integer, b text").
+	Arguments string `json:"arguments"`
+	// Result is the return type of the function. It is empty for procedures.
+	Result string `json:"result,omitempty"`
+	// Definition is the full CREATE FUNCTION statement.
+	Definition string `json:"definition"`
+}
+
+// Schema describes a database schema. Every list is ordered by name.
+type Schema struct {
+	Name      string           `json:"name"`
+	Tables    []SchemaTable    `json:"tables"`
+	Enums     []SchemaEnum     `json:"enums"`
+	Functions []SchemaFunction `json:"functions"`
+}
+
+// Table returns the table with the given name, if it exists in the schema.
+func (schema *Schema) Table(name string) (*SchemaTable, bool) {
+	for i := range schema.Tables {
+		if schema.Tables[i].Name == name {
+			return &schema.Tables[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Message renders the schema, for use with a quicklog.Logger.
+func (schema *Schema) Message() quicklog.Message {
+	tables := lo.Map(schema.Tables, func(table SchemaTable, _ int) asqlmessages.SchemaTable {
+		return asqlmessages.SchemaTable{
+			Name: table.Name,
+			Columns: lo.Map(table.Columns, func(column SchemaColumn, _ int) asqlmessages.SchemaColumn {
+				return asqlmessages.SchemaColumn(column)
+			}),
+			Indexes: lo.Map(table.Indexes, func(index SchemaIndex, _ int) asqlmessages.SchemaIndex {
+				return asqlmessages.SchemaIndex(index)
+			}),
+			ForeignKeys: lo.Map(table.ForeignKeys, func(foreignKey SchemaForeignKey, _ int) asqlmessages.SchemaForeignKey {
+				return asqlmessages.SchemaForeignKey(foreignKey)
+			}),
+			Constraints: lo.Map(table.Constraints, func(constraint SchemaConstraint, _ int) asqlmessages.SchemaConstraint {
+				return asqlmessages.SchemaConstraint(constraint)
+			}),
+			Triggers: lo.Map(table.Triggers, func(trigger SchemaTrigger, _ int) asqlmessages.SchemaTrigger {
+				return asqlmessages.SchemaTrigger(trigger)
+			}),
+		}
+	})
+
+	enums := lo.Map(schema.Enums, func(enum SchemaEnum, _ int) asqlmessages.SchemaEnum {
+		return asqlmessages.SchemaEnum(enum)
+	})
+
+	functions := lo.Map(schema.Functions, func(function SchemaFunction, _ int) asqlmessages.SchemaFunction {
+		return asqlmessages.SchemaFunction(function)
+	})
+
+	return asqlmessages.NewSchema(schema.Name, tables, enums, functions)
+}
+
+const inspectTablesQuery = `
+SELECT cls.relname AS table_name
+FROM pg_catalog.pg_class cls
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = cls.relnamespace
+WHERE nsp.nspname = ? AND cls.relkind IN ('r', 'p')
+ORDER BY cls.relname;
+`
+
+const inspectColumnsQuery = `
+SELECT
+  cls.relname AS table_name,
+  att.attname AS column_name,
+  pg_catalog.format_type(att.atttypid, att.atttypmod) AS data_type,
+  NOT att.attnotnull AS nullable,
+  COALESCE(pg_catalog.pg_get_expr(def.adbin, def.adrelid), '') AS column_default
+FROM pg_catalog.pg_attribute att
+JOIN pg_catalog.pg_class cls ON cls.oid = att.attrelid
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = cls.relnamespace
+LEFT JOIN pg_catalog.pg_attrdef def ON def.adrelid = att.attrelid AND def.adnum = att.attnum
+WHERE nsp.nspname = ? AND cls.relkind IN ('r', 'p') AND att.attnum > 0 AND NOT att.attisdropped
+ORDER BY cls.relname, att.attnum;
+`
+
+const inspectIndexesQuery = `
+SELECT
+  tbl.relname AS table_name,
+  idx.relname AS index_name,
+  ARRAY(
+    SELECT att.attname
+    FROM unnest(ind.indkey) WITH ORDINALITY AS keys(attnum, ord)
+    JOIN pg_catalog.pg_attribute att ON att.attrelid = ind.indrelid AND att.attnum = keys.attnum
+    ORDER BY keys.ord
+  ) AS columns,
+  ind.indisunique AS is_unique,
+  ind.indisprimary AS is_primary,
+  pg_catalog.pg_get_indexdef(ind.indexrelid) AS definition
+FROM pg_catalog.pg_index ind
+JOIN pg_catalog.pg_class tbl ON tbl.oid = ind.indrelid
+JOIN pg_catalog.pg_class idx ON idx.oid = ind.indexrelid
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = tbl.relnamespace
+WHERE nsp.nspname = ?
+ORDER BY tbl.relname, idx.relname;
+`
+
+const inspectForeignKeysQuery = `
+SELECT
+  tbl.relname AS table_name,
+  con.conname AS constraint_name,
+  ARRAY(
+    SELECT att.attname
+    FROM unnest(con.conkey) WITH ORDINALITY AS keys(attnum, ord)
+    JOIN pg_catalog.pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = keys.attnum
+    ORDER BY keys.ord
+  ) AS columns,
+  ref.relname AS referenced_table,
+  ARRAY(
+    SELECT att.attname
+    FROM unnest(con.confkey) WITH ORDINALITY AS keys(attnum, ord)
+    JOIN pg_catalog.pg_attribute att ON att.attrelid = con.confrelid AND att.attnum = keys.attnum
+    ORDER BY keys.ord
+  ) AS referenced_columns,
+  con.confupdtype::text AS on_update,
+  con.confdeltype::text AS on_delete
+FROM pg_catalog.pg_constraint con
+JOIN pg_catalog.pg_class tbl ON tbl.oid = con.conrelid
+JOIN pg_catalog.pg_class ref ON ref.oid = con.confrelid
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = tbl.relnamespace
+WHERE nsp.nspname = ? AND con.contype = 'f'
+ORDER BY tbl.relname, con.conname;
+`
+
+const inspectConstraintsQuery = `
+SELECT
+  tbl.relname AS table_name,
+  con.conname AS constraint_name,
+  con.contype::text AS constraint_type,
+  pg_catalog.pg_get_constraintdef(con.oid, true) AS definition
+FROM pg_catalog.pg_constraint con
+JOIN pg_catalog.pg_class tbl ON tbl.oid = con.conrelid
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = tbl.relnamespace
+WHERE nsp.nspname = ? AND con.contype IN ('p', 'u', 'c', 'x')
+ORDER BY tbl.relname, con.conname;
+`
+
+const inspectTriggersQuery = `
+SELECT
+  tbl.relname AS table_name,
+  trg.tgname AS trigger_name,
+  pg_catalog.pg_get_triggerdef(trg.oid, true) AS definition
+FROM pg_catalog.pg_trigger trg
+JOIN pg_catalog.pg_class tbl ON tbl.oid = trg.tgrelid
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = tbl.relnamespace
+WHERE nsp.nspname = ? AND NOT trg.tgisinternal
+ORDER BY tbl.relname, trg.tgname;
+`
+
+const inspectEnumsQuery = `
+SELECT
+  typ.typname AS enum_name,
+  ARRAY(
+    SELECT enm.enumlabel::text
+    FROM pg_catalog.pg_enum enm
+    WHERE enm.enumtypid = typ.oid
+    ORDER BY enm.enumsortorder
+  ) AS enum_values
+FROM pg_catalog.pg_type typ
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = typ.typnamespace
+WHERE nsp.nspname = ? AND typ.typtype = 'e'
+ORDER BY typ.typname;
+`
+
+const inspectFunctionsQuery = `
+SELECT
+  pro.proname AS function_name,
+  pg_catalog.pg_get_function_identity_arguments(pro.oid) AS arguments,
+  COALESCE(pg_catalog.pg_get_function_result(pro.oid), '') AS result,
+  pg_catalog.pg_get_functiondef(pro.oid) AS definition
+FROM pg_catalog.pg_proc pro
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = pro.pronamespace
+WHERE nsp.nspname = ? AND pro.prokind IN ('f', 'p')
+  AND NOT EXISTS (
+    SELECT 1 FROM pg_catalog.pg_depend dep
+    WHERE dep.classid = 'pg_catalog.pg_proc'::regclass AND dep.objid = pro.oid AND dep.deptype = 'e'
+  )
+ORDER BY pro.proname, arguments;
+`
+
+// Postgres encodes constraint types as single characters.
+var constraintTypes = map[string]string{
+	"p": "PRIMARY KEY",
+	"u": "UNIQUE",
+	"c": "CHECK",
+	"x": "EXCLUDE",
+}
+
+// Postgres encodes foreign key actions as single characters.
+var foreignKeyActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+type inspectedColumn struct {
+	TableName     string `bun:"table_name"`
+	ColumnName    string `bun:"column_name"`
+	DataType      string `bun:"data_type"`
+	Nullable      bool   `bun:"nullable"`
+	ColumnDefault string `bun:"column_default"`
+}
+
+type inspectedIndex struct {
+	TableName  string   `bun:"table_name"`
+	IndexName  string   `bun:"index_name"`
+	Columns    []string `bun:"columns,array"`
+	IsUnique   bool     `bun:"is_unique"`
+	IsPrimary  bool     `bun:"is_primary"`
+	Definition string   `bun:"definition"`
+}
+
+type inspectedForeignKey struct {
+	TableName         string   `bun:"table_name"`
+	ConstraintName    string   `bun:"constraint_name"`
+	Columns           []string `bun:"columns,array"`
+	ReferencedTable   string   `bun:"referenced_table"`
+	ReferencedColumns []string `bun:"referenced_columns,array"`
+	OnUpdate          string   `bun:"on_update"`
+	OnDelete          string   `bun:"on_delete"`
+}
+
+type inspectedConstraint struct {
+	TableName      string `bun:"table_name"`
+	ConstraintName string `bun:"constraint_name"`
+	ConstraintType string `bun:"constraint_type"`
+	Definition     string `bun:"definition"`
+}
+
+type inspectedTrigger struct {
+	TableName   string `bun:"table_name"`
+	TriggerName string `bun:"trigger_name"`
+	Definition  string `bun:"definition"`
+}
+
+type inspectedEnum struct {
+	EnumName   string   `bun:"enum_name"`
+	EnumValues []string `bun:"enum_values,array"`
+}
+
+type inspectedFunction struct {
+	FunctionName string `bun:"function_name"`
+	Arguments    string `bun:"arguments"`
+	Result       string `bun:"result"`
+	Definition   string `bun:"definition"`
+}
+
+// Inspect reads the structure of the given database schema: tables (with their columns, indexes, constraints and
+// triggers), enums and functions.
+func Inspect(ctx context.Context, database bun.IDB, schema string) (*Schema, error) {
+	var tableNames []string
+	if err := database.NewRaw(inspectTablesQuery, schema).Scan(ctx, &tableNames); err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+
+	var columns []inspectedColumn
+	if err := database.NewRaw(inspectColumnsQuery, schema).Scan(ctx, &columns); err != nil {
+		return nil, fmt.Errorf("list columns: %w", err)
+	}
+
+	var indexes []inspectedIndex
+	if err := database.NewRaw(inspectIndexesQuery, schema).Scan(ctx, &indexes); err != nil {
+		return nil, fmt.Errorf("list indexes: %w", err)
+	}
+
+	var foreignKeys []inspectedForeignKey
+	if err := database.NewRaw(inspectForeignKeysQuery, schema).Scan(ctx, &foreignKeys); err != nil {
+		return nil, fmt.Errorf("list foreign keys: %w", err)
+	}
+
+	var constraints []inspectedConstraint
+	if err := database.NewRaw(inspectConstraintsQuery, schema).Scan(ctx, &constraints); err != nil {
+		return nil, fmt.Errorf("list constraints: %w", err)
+	}
+
+	var triggers []inspectedTrigger
+	if err := database.NewRaw(inspectTriggersQuery, schema).Scan(ctx, &triggers); err != nil {
+		return nil, fmt.Errorf("list triggers: %w", err)
+	}
+
+	var enums []inspectedEnum
+	if err := database.NewRaw(inspectEnumsQuery, schema).Scan(ctx, &enums); err != nil {
+		return nil, fmt.Errorf("list enums: %w", err)
+	}
+
+	var functions []inspectedFunction
+	if err := database.NewRaw(inspectFunctionsQuery, schema).Scan(ctx, &functions); err != nil {
+		return nil, fmt.Errorf("list functions: %w", err)
+	}
+
+	output := &Schema{
+		Name:   schema,
+		Tables: make([]SchemaTable, len(tableNames)),
+		Enums: lo.Map(enums, func(enum inspectedEnum, _ int) SchemaEnum {
+			return SchemaEnum{Name: enum.EnumName, Values: enum.EnumValues}
+		}),
+		Functions: lo.Map(functions, func(function inspectedFunction, _ int) SchemaFunction {
+			return SchemaFunction{
+				Name:       function.FunctionName,
+				Arguments:  function.Arguments,
+				Result:     function.Result,
+				Definition: function.Definition,
+			}
+		}),
+	}
+
+	tablesIndexes := make(map[string]int, len(tableNames))
+
+	for i, tableName := range tableNames {
+		tablesIndexes[tableName] = i
+		output.Tables[i] = SchemaTable{
+			Name:        tableName,
+			Columns:     make([]SchemaColumn, 0),
+			Indexes:     make([]SchemaIndex, 0),
+			ForeignKeys: make([]SchemaForeignKey, 0),
+			Constraints: make([]SchemaConstraint, 0),
+			Triggers:    make([]SchemaTrigger, 0),
+		}
+	}
+
+	// Results are sorted by table, so appending preserves the order of each list.
+	for _, column := range columns {
+		table := &output.Tables[tablesIndexes[column.TableName]]
+		table.Columns = append(table.Columns, SchemaColumn{
+			Name:     column.ColumnName,
+			Type:     column.DataType,
+			Nullable: column.Nullable,
+			Default:  column.ColumnDefault,
+		})
+	}
+
+	for _, index := range indexes {
+		tableIndex, ok := tablesIndexes[index.TableName]
+		// Indexes can be set on materialized views, that are not listed as tables.
+		if !ok {
+			continue
+		}
+
+		table := &output.Tables[tableIndex]
+		table.Indexes = append(table.Indexes, SchemaIndex{
+			Name:       index.IndexName,
+			Columns:    index.Columns,
+			Unique:     index.IsUnique,
+			Primary:    index.IsPrimary,
+			Definition: index.Definition,
+		})
+	}
+
+	for _, foreignKey := range foreignKeys {
+		table := &output.Tables[tablesIndexes[foreignKey.TableName]]
+		table.ForeignKeys = append(table.ForeignKeys, SchemaForeignKey{
+			Name:              foreignKey.ConstraintName,
+			Columns:           foreignKey.Columns,
+			ReferencedTable:   foreignKey.ReferencedTable,
+			ReferencedColumns: foreignKey.ReferencedColumns,
+			OnUpdate:          foreignKeyActions[foreignKey.OnUpdate],
+			OnDelete:          foreignKeyActions[foreignKey.OnDelete],
+		})
+	}
+
+	for _, constraint := range constraints {
+		table := &output.Tables[tablesIndexes[constraint.TableName]]
+		table.Constraints = append(table.Constraints, SchemaConstraint{
+			Name:       constraint.ConstraintName,
+			Type:       constraintTypes[constraint.ConstraintType],
+			Definition: constraint.Definition,
+		})
+	}
+
+	for _, trigger := range triggers {
+		tableIndex, ok := tablesIndexes[trigger.TableName]
+		// Triggers can be set on views, that are not listed as tables.
+		if !ok {
+			continue
+		}
+
+		table := &output.Tables[tableIndex]
+		table.Triggers = append(table.Triggers, SchemaTrigger{
+			Name:       trigger.TriggerName,
+			Definition: trigger.Definition,
+		})
+	}
+
+	return output, nil
+}
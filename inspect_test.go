@@ -0,0 +1,106 @@
+package asql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/asql"
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestSchemaMessage(t *testing.T) {
+	schema := &asql.Schema{
+		Name: "public",
+		Tables: []asql.SchemaTable{
+			{
+				Name:    "table1",
+				Columns: []asql.SchemaColumn{{Name: "id", Type: "integer"}},
+				Constraints: []asql.SchemaConstraint{
+					{Name: "table1_pkey", Type: "PRIMARY KEY", Definition: "PRIMARY KEY (id)"},
+				},
+			},
+		},
+		Enums:     []asql.SchemaEnum{{Name: "role", Values: []string{"user", "admin"}}},
+		Functions: []asql.SchemaFunction{{Name: "touch", Result: "trigger"}},
+	}
+
+	table, ok := schema.Table("table1")
+	require.True(t, ok)
+	require.Equal(t, "table1", table.Name)
+
+	_, ok = schema.Table("table2")
+	require.False(t, ok)
+
+	require.Equal(t, "public.table1\n"+
+		"  Columns\n"+
+		"    id  integer  NOT NULL\n"+
+		"  Constraints\n"+
+		"    table1_pkey  PRIMARY KEY (id)\n"+
+		"Enums\n"+
+		"  public.role  (user, admin)\n"+
+		"Functions\n"+
+		"  public.touch()  RETURNS trigger\n", schema.Message().RenderTerminal())
+}
+
+func TestInspect(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	db := asqltest.NewDB(t, asqltest.WithIsolation(), asqltest.WithMigrations(databasemocks.MigrationsAll))
+
+	_, err := db.Exec(`
+		CREATE TABLE table4 (
+			id SERIAL PRIMARY KEY,
+			table1_id INT NOT NULL REFERENCES table1(id) ON DELETE CASCADE,
+			label TEXT
+		);
+		CREATE INDEX table4_label_idx ON table4 (label);
+	`)
+	require.NoError(t, err)
+
+	schema, err := asql.Inspect(context.Background(), db, "public")
+	require.NoError(t, err)
+
+	table1, ok := schema.Table("table1")
+	require.True(t, ok)
+	require.Equal(t, []asql.SchemaColumn{
+		{Name: "id", Type: "integer", Default: "nextval('table1_id_seq'::regclass)"},
+		{Name: "name", Type: "character varying(255)"},
+	}, table1.Columns)
+
+	table4, ok := schema.Table("table4")
+	require.True(t, ok)
+	require.Equal(t, []asql.SchemaColumn{
+		{Name: "id", Type: "integer", Default: "nextval('table4_id_seq'::regclass)"},
+		{Name: "table1_id", Type: "integer"},
+		{Name: "label", Type: "text", Nullable: true},
+	}, table4.Columns)
+	require.Equal(t, []asql.SchemaIndex{
+		{
+			Name:       "table4_label_idx",
+			Columns:    []string{"label"},
+			Definition: "CREATE INDEX table4_label_idx ON public.table4 USING btree (label)",
+		},
+		{
+			Name:       "table4_pkey",
+			Columns:    []string{"id"},
+			Unique:     true,
+			Primary:    true,
+			Definition: "CREATE UNIQUE INDEX table4_pkey ON public.table4 USING btree (id)",
+		},
+	}, table4.Indexes)
+	require.Equal(t, []asql.SchemaForeignKey{
+		{
+			Name:              "table4_table1_id_fkey",
+			Columns:           []string{"table1_id"},
+			ReferencedTable:   "table1",
+			ReferencedColumns: []string{"id"},
+			OnUpdate:          "NO ACTION",
+			OnDelete:          "CASCADE",
+		},
+	}, table4.ForeignKeys)
+}
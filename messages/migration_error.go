@@ -0,0 +1,186 @@
+package asqlmessages
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/a-novel-kit/quicklog"
+)
+
+// Number of lines to display before and after the line that caused the error.
+const migrationErrorContextLines = 2
+
+// MigrationFailure describes a migration that could not be applied.
+type MigrationFailure struct {
+	// File is the path of the migration file that failed.
+	File string
+	// SQL is the content of the migration file. It is used to show the context of the error.
+	SQL string
+
+	// Postgres error fields. They are empty when the error did not come from the database.
+	Code    string
+	Message string
+	Detail  string
+	Hint    string
+	// Position is the 1-based index of the character where the error occurred in the SQL. It is 0 when unknown.
+	Position int
+
+	// Err is the original error. Its message is displayed when Message is empty.
+	Err error
+}
+
+type migrationErrorMessage struct {
+	failure MigrationFailure
+
+	quicklog.Message
+}
+
+type migrationErrorSnippetLine struct {
+	number  int
+	content string
+}
+
+// Locate the error position in the SQL. It returns the 1-based line and column, or 0 if the position is unknown.
+func (message *migrationErrorMessage) locate() (int, int) {
+	if message.failure.Position <= 0 || message.failure.SQL == "" {
+		return 0, 0
+	}
+
+	// Postgres positions are expressed in characters, not bytes.
+	runes := []rune(message.failure.SQL)
+	if message.failure.Position > len(runes) {
+		return 0, 0
+	}
+
+	before := string(runes[:message.failure.Position-1])
+	line := strings.Count(before, "\n") + 1
+	column := len([]rune(before[strings.LastIndex(before, "\n")+1:])) + 1
+
+	return line, column
+}
+
+// Return the lines surrounding the error.
+func (message *migrationErrorMessage) snippet(line int) []migrationErrorSnippetLine {
+	if line == 0 {
+		return nil
+	}
+
+	lines := strings.Split(message.failure.SQL, "\n")
+	start := max(line-migrationErrorContextLines, 1)
+	end := min(line+migrationErrorContextLines, len(lines))
+
+	output := make([]migrationErrorSnippetLine, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		output = append(output, migrationErrorSnippetLine{number: i, content: lines[i-1]})
+	}
+
+	return output
+}
+
+func (message *migrationErrorMessage) errorMessage() string {
+	if message.failure.Message != "" {
+		return message.failure.Message
+	}
+
+	if message.failure.Err != nil {
+		return message.failure.Err.Error()
+	}
+
+	return "unknown error"
+}
+
+func (message *migrationErrorMessage) RenderTerminal() string {
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	faintStyle := lipgloss.NewStyle().Faint(true)
+
+	output := errorStyle.Bold(true).Render("✗ Migration "+message.failure.File+" failed") + "\n"
+
+	summary := message.errorMessage()
+	if message.failure.Code != "" {
+		summary = message.failure.Code + ": " + summary
+	}
+
+	output += "  " + errorStyle.Render(summary) + "\n"
+
+	if message.failure.Detail != "" {
+		output += "  " + faintStyle.Render("Detail: ") + message.failure.Detail + "\n"
+	}
+
+	if message.failure.Hint != "" {
+		output += "  " + faintStyle.Render("Hint: ") + message.failure.Hint + "\n"
+	}
+
+	line, column := message.locate()
+	snippet := message.snippet(line)
+	if len(snippet) == 0 {
+		return output
+	}
+
+	// Align line numbers on the widest one.
+	gutterWidth := len(strconv.Itoa(snippet[len(snippet)-1].number))
+
+	output += "\n"
+
+	for _, snippetLine := range snippet {
+		gutter := faintStyle.Render(fmt.Sprintf("  %*d | ", gutterWidth, snippetLine.number))
+
+		if snippetLine.number != line {
+			output += gutter + faintStyle.Render(snippetLine.content) + "\n"
+			continue
+		}
+
+		output += gutter + snippetLine.content + "\n"
+		output += faintStyle.Render(fmt.Sprintf("  %*s | ", gutterWidth, "")) +
+			strings.Repeat(" ", column-1) + errorStyle.Bold(true).Render("^") + "\n"
+	}
+
+	return output
+}
+
+func (message *migrationErrorMessage) RenderJSON() map[string]interface{} {
+	output := map[string]interface{}{
+		"file":    message.failure.File,
+		"message": message.errorMessage(),
+	}
+
+	if message.failure.Code != "" {
+		output["code"] = message.failure.Code
+	}
+
+	if message.failure.Detail != "" {
+		output["detail"] = message.failure.Detail
+	}
+
+	if message.failure.Hint != "" {
+		output["hint"] = message.failure.Hint
+	}
+
+	line, column := message.locate()
+	if line == 0 {
+		return output
+	}
+
+	output["position"] = message.failure.Position
+	output["line"] = line
+	output["column"] = column
+
+	snippet := make([]interface{}, 0)
+	for _, snippetLine := range message.snippet(line) {
+		snippet = append(snippet, map[string]interface{}{
+			"line":    snippetLine.number,
+			"content": snippetLine.content,
+		})
+	}
+
+	output["snippet"] = snippet
+
+	return output
+}
+
+// NewMigrationError renders a failed migration, along with the SQL surrounding the error when its position is known.
+func NewMigrationError(failure MigrationFailure) quicklog.Message {
+	return &migrationErrorMessage{failure: failure}
+}
@@ -0,0 +1,99 @@
+package asqlmessages_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	asqlmessages "github.com/a-novel-kit/asql/messages"
+)
+
+func TestMigrationError(t *testing.T) {
+	sql := "CREATE TABLE table1 (\n" +
+		"    id SERIAL PRIMARY KEY,\n" +
+		"    name VARCHAR(255) NOT NULLL,\n" +
+		"    age INT\n" +
+		");\n"
+
+	t.Run("WithPosition", func(t *testing.T) {
+		content := asqlmessages.NewMigrationError(asqlmessages.MigrationFailure{
+			File:     "20200101120000_migration_1.up.sql",
+			SQL:      sql,
+			Code:     "42601",
+			Message:  `syntax error at or near "NULLL"`,
+			Hint:     "check your syntax",
+			Position: 76,
+		})
+
+		expectConsole := "✗ Migration 20200101120000_migration_1.up.sql failed\n" +
+			"  42601: syntax error at or near \"NULLL\"\n" +
+			"  Hint: check your syntax\n" +
+			"\n" +
+			"  1 | CREATE TABLE table1 (\n" +
+			"  2 |     id SERIAL PRIMARY KEY,\n" +
+			"  3 |     name VARCHAR(255) NOT NULLL,\n" +
+			"    |                           ^\n" +
+			"  4 |     age INT\n" +
+			"  5 | );\n"
+		expectJSON := map[string]interface{}{
+			"file":     "20200101120000_migration_1.up.sql",
+			"message":  `syntax error at or near "NULLL"`,
+			"code":     "42601",
+			"hint":     "check your syntax",
+			"position": 76,
+			"line":     3,
+			"column":   27,
+			"snippet": []interface{}{
+				map[string]interface{}{"line": 1, "content": "CREATE TABLE table1 ("},
+				map[string]interface{}{"line": 2, "content": "    id SERIAL PRIMARY KEY,"},
+				map[string]interface{}{"line": 3, "content": "    name VARCHAR(255) NOT NULLL,"},
+				map[string]interface{}{"line": 4, "content": "    age INT"},
+				map[string]interface{}{"line": 5, "content": ");"},
+			},
+		}
+
+		require.Equal(t, expectConsole, content.RenderTerminal())
+		require.Equal(t, expectJSON, content.RenderJSON())
+	})
+
+	t.Run("NoPosition", func(t *testing.T) {
+		content := asqlmessages.NewMigrationError(asqlmessages.MigrationFailure{
+			File:    "20200101120000_migration_1.up.sql",
+			SQL:     sql,
+			Code:    "42P07",
+			Message: `relation "table1" already exists`,
+			Detail:  "some detail",
+		})
+
+		expectConsole := "✗ Migration 20200101120000_migration_1.up.sql failed\n" +
+			"  42P07: relation \"table1\" already exists\n" +
+			"  Detail: some detail\n"
+		expectJSON := map[string]interface{}{
+			"file":    "20200101120000_migration_1.up.sql",
+			"message": `relation "table1" already exists`,
+			"code":    "42P07",
+			"detail":  "some detail",
+		}
+
+		require.Equal(t, expectConsole, content.RenderTerminal())
+		require.Equal(t, expectJSON, content.RenderJSON())
+	})
+
+	t.Run("NonDatabaseError", func(t *testing.T) {
+		content := asqlmessages.NewMigrationError(asqlmessages.MigrationFailure{
+			File: "20200101120000_migration_1.up.sql",
+			Err:  errors.New("connection reset"),
+		})
+
+		expectConsole := "✗ Migration 20200101120000_migration_1.up.sql failed\n" +
+			"  connection reset\n"
+		expectJSON := map[string]interface{}{
+			"file":    "20200101120000_migration_1.up.sql",
+			"message": "connection reset",
+		}
+
+		require.Equal(t, expectConsole, content.RenderTerminal())
+		require.Equal(t, expectJSON, content.RenderJSON())
+	})
+}
@@ -3,7 +3,6 @@ package asqlmessages
 import (
 	"fmt"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +24,8 @@ type migrationsMessage struct {
 	migrations []migrate.Migration
 	// If set, the last applied migration will be highlighted.
 	lastAppliedGroup int64
+	// Names of the migrations that were, or are about to be, applied out of order.
+	outOfOrder map[string]bool
 
 	quicklog.Message
 }
@@ -90,6 +91,16 @@ func (migrations *migrationsMessage) printMigrationItem(migration migrate.Migrat
 
 	applied := migration.MigratedAt != time.Time{}
 
+	// Out-of-order migrations are always highlighted, so they can be reviewed.
+	if migrations.outOfOrder[migration.Name] {
+		outOfOrderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+		if applied {
+			migrationName += " (" + migration.MigratedAt.Format(time.RFC3339) + ")"
+		}
+
+		return outOfOrderStyle.Render(" "+migrationName) + " " + outOfOrderStyle.Bold(true).Render("⚠ out of order")
+	}
+
 	// If the file has a migration date set, it has been migrated.
 	if applied {
 		// Show the migration date.
@@ -144,32 +155,30 @@ func (migrations *migrationsMessage) RenderJSON() map[string]interface{} {
 		return nil
 	}
 
-	output := make(map[string]interface{})
+	return migrations.report().toMap()
+}
 
-	for _, migration := range migrations.migrations {
-		mapKey := strconv.FormatInt(migration.GroupID, 10)
-		if _, ok := output[mapKey]; !ok {
-			output[mapKey] = make([]interface{}, 0)
-		}
+// MigrationsOption customizes the rendering of a list of migrations.
+type MigrationsOption func(message *migrationsMessage)
 
-		elem := map[string]interface{}{
-			"name":    migration.Name,
-			"comment": migration.Comment,
+// WithOutOfOrder highlights the given migrations as out of order. They are usually the ones reported by the
+// out-of-order check of Migrate, through MigrationResult.OutOfOrder or a MigrationSink.
+func WithOutOfOrder(migrations ...migrate.Migration) MigrationsOption {
+	return func(message *migrationsMessage) {
+		for _, migration := range migrations {
+			message.outOfOrder[migration.Name] = true
 		}
-
-		applied := migration.MigratedAt != time.Time{}
-		if applied {
-			elem["migrated_at"] = migration.MigratedAt.Format(time.RFC3339)
-		}
-
-		output[mapKey] = append(output[mapKey].([]interface{}), elem)
 	}
-
-	return output
 }
 
-func NewMigrations(migrations []migrate.Migration, lastAppliedGroup int64) quicklog.Message {
+// Sort the migrations, and prepare them for rendering under any format.
+func newMigrationsMessage(
+	migrations []migrate.Migration, lastAppliedGroup int64, opts ...MigrationsOption,
+) *migrationsMessage {
 	if len(migrations) > 0 {
+		// The migrations of the caller are left untouched, so they keep their order.
+		migrations = slices.Clone(migrations)
+
 		// Sort groups by groupID.
 		slices.SortFunc(migrations, func(migrationA, migrationB migrate.Migration) int {
 			// Sort by migration date, last migrated first.
@@ -185,8 +194,21 @@ func NewMigrations(migrations []migrate.Migration, lastAppliedGroup int64) quick
 		})
 	}
 
-	return &migrationsMessage{
+	message := &migrationsMessage{
 		migrations:       migrations,
 		lastAppliedGroup: lastAppliedGroup,
+		outOfOrder:       make(map[string]bool),
 	}
+
+	for _, opt := range opts {
+		opt(message)
+	}
+
+	return message
+}
+
+// NewMigrations renders a list of migrations, grouped by the batch they were applied in. Migrations passed with
+// WithOutOfOrder are highlighted.
+func NewMigrations(migrations []migrate.Migration, lastAppliedGroup int64, opts ...MigrationsOption) quicklog.Message {
+	return newMigrationsMessage(migrations, lastAppliedGroup, opts...)
 }
@@ -0,0 +1,135 @@
+package asqlmessages
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun/migrate"
+)
+
+// MigrationsReportVersion is the version of the JSON structure produced by MigrationsReport. It is increased on every
+// breaking change of the structure.
+const MigrationsReportVersion = 1
+
+// MigrationStatus tells whether a migration, or a group of migrations, has been applied.
+type MigrationStatus string
+
+const (
+	MigrationStatusApplied MigrationStatus = "applied"
+	MigrationStatusPending MigrationStatus = "pending"
+)
+
+// MigrationsReportItem is a single migration file.
+type MigrationsReportItem struct {
+	// Name is the timestamp part of the migration file name.
+	Name string `json:"name"`
+	// Comment is the rest of the migration file name, without the extension.
+	Comment string `json:"comment"`
+	// Status tells whether the migration has been applied.
+	Status MigrationStatus `json:"status"`
+	// MigratedAt is only set for applied migrations.
+	MigratedAt *time.Time `json:"migrated_at,omitempty"`
+	// OutOfOrder is set when the migration was, or is about to be, applied after a more recent one.
+	OutOfOrder bool `json:"out_of_order,omitempty"`
+}
+
+// MigrationsReportGroup is a group of migrations, applied in a single batch.
+type MigrationsReportGroup struct {
+	// GroupID is 0 for migrations that have never been assigned a group.
+	GroupID int64 `json:"group_id"`
+	// Status tells whether the group has been applied. Every migration in a group shares the same status.
+	Status MigrationStatus `json:"status"`
+	// IsLastApplied is set for the group applied during the last run.
+	IsLastApplied bool                   `json:"is_last_applied"`
+	Migrations    []MigrationsReportItem `json:"migrations"`
+}
+
+// MigrationsReport is the JSON representation of the migrations message. Groups are ordered from the most recently
+// applied to pending ones, and migrations within a group from the most recent to the oldest.
+//
+//	{
+//	  "version": 1,
+//	  "groups": [
+//	    {
+//	      "group_id": 2,
+//	      "status": "applied",
+//	      "is_last_applied": true,
+//	      "migrations": [
+//	        {"name": "20200101130000", "comment": "migration_2", "status": "applied", "migrated_at": "2020-01-02T13:00:00Z"}
+//	      ]
+//	    },
+//	    {
+//	      "group_id": 0,
+//	      "status": "pending",
+//	      "is_last_applied": false,
+//	      "migrations": [{"name": "20200101140000", "comment": "migration_3", "status": "pending"}]
+//	    }
+//	  ]
+//	}
+type MigrationsReport struct {
+	Version int                     `json:"version"`
+	Groups  []MigrationsReportGroup `json:"groups"`
+}
+
+func (migrations *migrationsMessage) report() MigrationsReport {
+	report := MigrationsReport{
+		Version: MigrationsReportVersion,
+		Groups:  make([]MigrationsReportGroup, 0),
+	}
+
+	if len(migrations.migrations) == 0 {
+		return report
+	}
+
+	for _, group := range migrations.getSortedMigrations() {
+		reportGroup := MigrationsReportGroup{
+			GroupID:    group.groupID,
+			Status:     MigrationStatusPending,
+			Migrations: make([]MigrationsReportItem, len(group.migrations)),
+		}
+
+		if group.groupID != 0 && group.migrations[0].MigratedAt != (time.Time{}) {
+			reportGroup.Status = MigrationStatusApplied
+			reportGroup.IsLastApplied = group.groupID == migrations.lastAppliedGroup
+		}
+
+		for i, migration := range group.migrations {
+			item := MigrationsReportItem{
+				Name:       migration.Name,
+				Comment:    migration.Comment,
+				Status:     MigrationStatusPending,
+				OutOfOrder: migrations.outOfOrder[migration.Name],
+			}
+
+			if migration.MigratedAt != (time.Time{}) {
+				migratedAt := migration.MigratedAt.UTC()
+				item.Status = MigrationStatusApplied
+				item.MigratedAt = &migratedAt
+			}
+
+			reportGroup.Migrations[i] = item
+		}
+
+		report.Groups = append(report.Groups, reportGroup)
+	}
+
+	return report
+}
+
+// NewMigrationsReport returns the JSON representation of a list of migrations, as rendered by NewMigrations.
+func NewMigrationsReport(
+	migrations []migrate.Migration, lastAppliedGroup int64, opts ...MigrationsOption,
+) MigrationsReport {
+	return newMigrationsMessage(migrations, lastAppliedGroup, opts...).report()
+}
+
+// Convert the report to a generic map, as expected by loggers.
+func (report MigrationsReport) toMap() map[string]interface{} {
+	// The report is only made of serializable types, so encoding cannot fail.
+	encoded, _ := json.Marshal(report)
+
+	var output map[string]interface{}
+	_ = json.Unmarshal(encoded, &output)
+
+	return output
+}
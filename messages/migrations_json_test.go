@@ -0,0 +1,78 @@
+package asqlmessages_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun/migrate"
+
+	asqlmessages "github.com/a-novel-kit/asql/messages"
+)
+
+func TestMigrationsReport(t *testing.T) {
+	migrations := []migrate.Migration{
+		{
+			ID:         1,
+			Name:       "20200101120000",
+			Comment:    "migration_1",
+			GroupID:    1,
+			MigratedAt: time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:    "20200101130000",
+			Comment: "migration_2",
+		},
+	}
+
+	migratedAt := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	expect := asqlmessages.MigrationsReport{
+		Version: asqlmessages.MigrationsReportVersion,
+		Groups: []asqlmessages.MigrationsReportGroup{
+			{
+				GroupID:       1,
+				Status:        asqlmessages.MigrationStatusApplied,
+				IsLastApplied: true,
+				Migrations: []asqlmessages.MigrationsReportItem{
+					{
+						Name:       "20200101120000",
+						Comment:    "migration_1",
+						Status:     asqlmessages.MigrationStatusApplied,
+						MigratedAt: &migratedAt,
+					},
+				},
+			},
+			{
+				GroupID: 0,
+				Status:  asqlmessages.MigrationStatusPending,
+				Migrations: []asqlmessages.MigrationsReportItem{
+					{
+						Name:    "20200101130000",
+						Comment: "migration_2",
+						Status:  asqlmessages.MigrationStatusPending,
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, expect, asqlmessages.NewMigrationsReport(migrations, 1))
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		// The output of RenderJSON must be decodable into the report structure.
+		encoded, err := json.Marshal(asqlmessages.NewMigrations(migrations, 1).RenderJSON())
+		require.NoError(t, err)
+
+		var decoded asqlmessages.MigrationsReport
+		require.NoError(t, json.Unmarshal(encoded, &decoded))
+		require.Equal(t, expect, decoded)
+	})
+
+	t.Run("NoMigrations", func(t *testing.T) {
+		require.Equal(t, asqlmessages.MigrationsReport{
+			Version: asqlmessages.MigrationsReportVersion,
+			Groups:  []asqlmessages.MigrationsReportGroup{},
+		}, asqlmessages.NewMigrationsReport(nil, 0))
+	})
+}
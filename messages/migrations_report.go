@@ -0,0 +1,159 @@
+package asqlmessages
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun/migrate"
+)
+
+// Stylesheet of the standalone HTML report.
+const migrationsReportHTMLStyle = `body { font-family: sans-serif; margin: 2em; color: #222; }
+ul.groups { list-style: none; padding: 0; }
+ul.groups > li { margin-bottom: 1em; }
+.group { font-weight: bold; }
+.group.applied { color: #1a7f37; }
+.group.pending, .group.none { color: #888; }
+.last-applied { background: #eaf5ff; }
+.migration.pending { color: #888; }
+.migration code { font-size: 0.95em; }
+.migrated-at { color: #666; }
+.out-of-order { color: #bf6a02; font-weight: bold; }`
+
+// Get the title of a migration group, with its applied marker, in plain text.
+func (migrations *migrationsMessage) groupTitleText(group migrationGroup) string {
+	if group.groupID == 0 {
+		return "No group"
+	}
+
+	if group.migrations[0].MigratedAt == (time.Time{}) {
+		return fmt.Sprintf("✗ Group %v", group.groupID)
+	}
+
+	return fmt.Sprintf("✓ Group %v", group.groupID)
+}
+
+func (migrations *migrationsMessage) renderMarkdown() string {
+	if len(migrations.migrations) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	for _, group := range migrations.getSortedMigrations() {
+		title := migrations.groupTitleText(group)
+
+		if group.groupID != 0 && group.groupID == migrations.lastAppliedGroup {
+			builder.WriteString("- **" + title + "** _(last applied)_\n")
+		} else {
+			builder.WriteString("- " + title + "\n")
+		}
+
+		for _, migration := range group.migrations {
+			builder.WriteString("  - `" + migration.Name + "_" + migration.Comment + "`")
+
+			if migration.MigratedAt != (time.Time{}) {
+				builder.WriteString(" — " + migration.MigratedAt.Format(time.RFC3339))
+			} else {
+				builder.WriteString(" — pending")
+			}
+
+			if migrations.outOfOrder[migration.Name] {
+				builder.WriteString(" ⚠ **out of order**")
+			}
+
+			builder.WriteString("\n")
+		}
+	}
+
+	return builder.String()
+}
+
+func (migrations *migrationsMessage) renderHTML(title string) string {
+	var builder strings.Builder
+
+	builder.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	builder.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	builder.WriteString("<style>\n" + migrationsReportHTMLStyle + "\n</style>\n")
+	builder.WriteString("</head>\n<body>\n")
+	builder.WriteString("<h1>" + html.EscapeString(title) + "</h1>\n")
+
+	if len(migrations.migrations) == 0 {
+		builder.WriteString("<p>No migrations.</p>\n</body>\n</html>\n")
+		return builder.String()
+	}
+
+	builder.WriteString("<ul class=\"groups\">\n")
+
+	for _, group := range migrations.getSortedMigrations() {
+		groupClass := "none"
+		if group.groupID != 0 {
+			groupClass = "pending"
+			if group.migrations[0].MigratedAt != (time.Time{}) {
+				groupClass = "applied"
+			}
+		}
+
+		isLastApplied := group.groupID != 0 && group.groupID == migrations.lastAppliedGroup
+		if isLastApplied {
+			builder.WriteString("<li class=\"last-applied\">\n")
+		} else {
+			builder.WriteString("<li>\n")
+		}
+
+		builder.WriteString(fmt.Sprintf(
+			"<span class=\"group %s\">%s</span>", groupClass, html.EscapeString(migrations.groupTitleText(group)),
+		))
+		if isLastApplied {
+			builder.WriteString(" <em>(last applied)</em>")
+		}
+
+		builder.WriteString("\n<ul>\n")
+
+		for _, migration := range group.migrations {
+			applied := migration.MigratedAt != (time.Time{})
+
+			builder.WriteString(fmt.Sprintf(
+				"<li class=\"migration %s\"><code>%s</code>",
+				lo.Ternary(applied, "applied", "pending"),
+				html.EscapeString(migration.Name+"_"+migration.Comment),
+			))
+
+			if applied {
+				builder.WriteString(fmt.Sprintf(
+					" <time class=\"migrated-at\" datetime=\"%[1]s\">%[1]s</time>", migration.MigratedAt.Format(time.RFC3339),
+				))
+			}
+
+			if migrations.outOfOrder[migration.Name] {
+				builder.WriteString(" <span class=\"out-of-order\">⚠ out of order</span>")
+			}
+
+			builder.WriteString("</li>\n")
+		}
+
+		builder.WriteString("</ul>\n</li>\n")
+	}
+
+	builder.WriteString("</ul>\n</body>\n</html>\n")
+
+	return builder.String()
+}
+
+// RenderMigrationsMarkdown renders migrations as a Markdown list, grouped the same way as NewMigrations. It is
+// suited for pull-request comments and release notes.
+func RenderMigrationsMarkdown(
+	migrations []migrate.Migration, lastAppliedGroup int64, opts ...MigrationsOption,
+) string {
+	return newMigrationsMessage(migrations, lastAppliedGroup, opts...).renderMarkdown()
+}
+
+// RenderMigrationsHTML renders migrations as a standalone HTML document, grouped the same way as NewMigrations.
+func RenderMigrationsHTML(
+	title string, migrations []migrate.Migration, lastAppliedGroup int64, opts ...MigrationsOption,
+) string {
+	return newMigrationsMessage(migrations, lastAppliedGroup, opts...).renderHTML(title)
+}
@@ -0,0 +1,82 @@
+package asqlmessages_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun/migrate"
+
+	asqlmessages "github.com/a-novel-kit/asql/messages"
+)
+
+func reportFixtures() []migrate.Migration {
+	return []migrate.Migration{
+		{
+			ID:         1,
+			Name:       "20200101120000",
+			Comment:    "migration_1",
+			GroupID:    1,
+			MigratedAt: time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:         2,
+			Name:       "20200101140000",
+			Comment:    "migration_3",
+			GroupID:    2,
+			MigratedAt: time.Date(2020, 1, 2, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:    "20200101130000",
+			Comment: "migration_<2>",
+		},
+	}
+}
+
+func reportOutOfOrder() asqlmessages.MigrationsOption {
+	return asqlmessages.WithOutOfOrder(migrate.Migration{Name: "20200101130000"})
+}
+
+func TestRenderMigrationsMarkdown(t *testing.T) {
+	t.Run("Render", func(t *testing.T) {
+		expect := "- **✓ Group 2** _(last applied)_\n" +
+			"  - `20200101140000_migration_3` — 2020-01-02T13:00:00Z\n" +
+			"- ✓ Group 1\n" +
+			"  - `20200101120000_migration_1` — 2020-01-02T12:00:00Z\n" +
+			"- No group\n" +
+			"  - `20200101130000_migration_<2>` — pending ⚠ **out of order**\n"
+
+		require.Equal(t, expect, asqlmessages.RenderMigrationsMarkdown(reportFixtures(), 2, reportOutOfOrder()))
+	})
+
+	t.Run("NoMigrations", func(t *testing.T) {
+		require.Equal(t, "", asqlmessages.RenderMigrationsMarkdown(nil, 0))
+	})
+}
+
+func TestRenderMigrationsHTML(t *testing.T) {
+	t.Run("Render", func(t *testing.T) {
+		output := asqlmessages.RenderMigrationsHTML("Migrations & co", reportFixtures(), 2, reportOutOfOrder())
+
+		require.Contains(t, output, "<!DOCTYPE html>")
+		require.Contains(t, output, "<title>Migrations &amp; co</title>")
+		require.Contains(t, output, "<li class=\"last-applied\">\n"+
+			"<span class=\"group applied\">✓ Group 2</span> <em>(last applied)</em>\n"+
+			"<ul>\n"+
+			"<li class=\"migration applied\"><code>20200101140000_migration_3</code> "+
+			"<time class=\"migrated-at\" datetime=\"2020-01-02T13:00:00Z\">2020-01-02T13:00:00Z</time></li>\n"+
+			"</ul>\n</li>\n")
+		require.Contains(t, output, "<li>\n"+
+			"<span class=\"group none\">No group</span>\n"+
+			"<ul>\n"+
+			"<li class=\"migration pending\"><code>20200101130000_migration_&lt;2&gt;</code> "+
+			"<span class=\"out-of-order\">⚠ out of order</span></li>\n"+
+			"</ul>\n</li>\n")
+	})
+
+	t.Run("NoMigrations", func(t *testing.T) {
+		output := asqlmessages.RenderMigrationsHTML("Migrations", nil, 0)
+
+		require.Contains(t, output, "<p>No migrations.</p>")
+	})
+}
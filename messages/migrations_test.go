@@ -42,23 +42,39 @@ func TestMigrations(t *testing.T) {
 			"     - 20200101120000__migration_2 (2020-01-02T12:00:00Z)\n" +
 			"     - 20200101120000__migration_1 (2020-01-02T12:00:00Z)\n"
 		expectJSON := map[string]interface{}{
-			"1": []interface{}{
+			"version": float64(1),
+			"groups": []interface{}{
 				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_2",
-					"migrated_at": "2020-01-02T12:00:00Z",
+					"group_id":        float64(2),
+					"status":          "applied",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_3",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T13:00:00Z",
+						},
+					},
 				},
 				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_1",
-					"migrated_at": "2020-01-02T12:00:00Z",
-				},
-			},
-			"2": []interface{}{
-				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_3",
-					"migrated_at": "2020-01-02T13:00:00Z",
+					"group_id":        float64(1),
+					"status":          "applied",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_2",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_1",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+					},
 				},
 			},
 		}
@@ -74,6 +90,18 @@ func TestMigrations(t *testing.T) {
 		require.Nil(t, content.RenderJSON())
 	})
 
+	t.Run("KeepsInputOrder", func(t *testing.T) {
+		migrations := []migrate.Migration{
+			{Name: "20200101120000", GroupID: 1, MigratedAt: time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)},
+			{Name: "20200101130000", GroupID: 2, MigratedAt: time.Date(2020, 1, 2, 13, 0, 0, 0, time.UTC)},
+		}
+
+		asqlmessages.NewMigrations(migrations, 2)
+
+		require.Equal(t, "20200101120000", migrations[0].Name)
+		require.Equal(t, "20200101130000", migrations[1].Name)
+	})
+
 	t.Run("NonAppliedGroup", func(t *testing.T) {
 		content := asqlmessages.NewMigrations([]migrate.Migration{
 			{
@@ -104,22 +132,38 @@ func TestMigrations(t *testing.T) {
 			" ✗ Group 2\n" +
 			"     - 20200101120000__migration_3\n"
 		expectJSON := map[string]interface{}{
-			"1": []interface{}{
-				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_2",
-					"migrated_at": "2020-01-02T12:00:00Z",
-				},
+			"version": float64(1),
+			"groups": []interface{}{
 				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_1",
-					"migrated_at": "2020-01-02T12:00:00Z",
+					"group_id":        float64(1),
+					"status":          "applied",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_2",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_1",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+					},
 				},
-			},
-			"2": []interface{}{
 				map[string]interface{}{
-					"name":    "20200101120000",
-					"comment": "_migration_3",
+					"group_id":        float64(2),
+					"status":          "pending",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":    "20200101120000",
+							"comment": "_migration_3",
+							"status":  "pending",
+						},
+					},
 				},
 			},
 		}
@@ -157,21 +201,37 @@ func TestMigrations(t *testing.T) {
 			"     - 20200101120000__migration_2\n" +
 			"     - 20200101120000__migration_1\n"
 		expectJSON := map[string]interface{}{
-			"0": []interface{}{
-				map[string]interface{}{
-					"name":    "20200101120000",
-					"comment": "_migration_2",
-				},
+			"version": float64(1),
+			"groups": []interface{}{
 				map[string]interface{}{
-					"name":    "20200101120000",
-					"comment": "_migration_1",
+					"group_id":        float64(1),
+					"status":          "applied",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_3",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+					},
 				},
-			},
-			"1": []interface{}{
 				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_3",
-					"migrated_at": "2020-01-02T12:00:00Z",
+					"group_id":        float64(0),
+					"status":          "pending",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":    "20200101120000",
+							"comment": "_migration_2",
+							"status":  "pending",
+						},
+						map[string]interface{}{
+							"name":    "20200101120000",
+							"comment": "_migration_1",
+							"status":  "pending",
+						},
+					},
 				},
 			},
 		}
@@ -211,23 +271,135 @@ func TestMigrations(t *testing.T) {
 			"     - 20200101120000__migration_2 (2020-01-02T12:00:00Z)\n" +
 			"     - 20200101120000__migration_1 (2020-01-02T12:00:00Z)\n"
 		expectJSON := map[string]interface{}{
-			"1": []interface{}{
+			"version": float64(1),
+			"groups": []interface{}{
 				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_2",
-					"migrated_at": "2020-01-02T12:00:00Z",
+					"group_id":        float64(2),
+					"status":          "applied",
+					"is_last_applied": true,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_3",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T13:00:00Z",
+						},
+					},
 				},
 				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_1",
-					"migrated_at": "2020-01-02T12:00:00Z",
+					"group_id":        float64(1),
+					"status":          "applied",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_2",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "_migration_1",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+					},
 				},
 			},
-			"2": []interface{}{
+		}
+
+		require.Equal(t, expectConsole, content.RenderTerminal())
+		require.Equal(t, expectJSON, content.RenderJSON())
+	})
+
+	t.Run("OutOfOrder", func(t *testing.T) {
+		// Out-of-order migrations are the ones reported by the migration run.
+		content := asqlmessages.NewMigrations([]migrate.Migration{
+			{
+				ID:         1,
+				Name:       "20200101120000",
+				Comment:    "migration_1",
+				GroupID:    1,
+				MigratedAt: time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC),
+			},
+			{
+				ID:         2,
+				Name:       "20200101140000",
+				Comment:    "migration_3",
+				GroupID:    1,
+				MigratedAt: time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC),
+			},
+			{
+				ID:         3,
+				Name:       "20200101130000",
+				Comment:    "migration_2",
+				GroupID:    2,
+				MigratedAt: time.Date(2020, 1, 2, 13, 0, 0, 0, time.UTC),
+			},
+			{
+				Name:    "20200101125000",
+				Comment: "migration_4",
+			},
+		}, 2, asqlmessages.WithOutOfOrder(
+			migrate.Migration{Name: "20200101130000"},
+			migrate.Migration{Name: "20200101125000"},
+		))
+
+		expectConsole := " ✓ Group 2\n" +
+			"     - 20200101130000_migration_2 (2020-01-02T13:00:00Z) ⚠ out of order\n" +
+			" ✓ Group 1\n" +
+			"     - 20200101140000_migration_3 (2020-01-02T12:00:00Z)\n" +
+			"     - 20200101120000_migration_1 (2020-01-02T12:00:00Z)\n" +
+			" No group\n" +
+			"     - 20200101125000_migration_4 ⚠ out of order\n"
+		expectJSON := map[string]interface{}{
+			"version": float64(1),
+			"groups": []interface{}{
+				map[string]interface{}{
+					"group_id":        float64(2),
+					"status":          "applied",
+					"is_last_applied": true,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":         "20200101130000",
+							"comment":      "migration_2",
+							"status":       "applied",
+							"migrated_at":  "2020-01-02T13:00:00Z",
+							"out_of_order": true,
+						},
+					},
+				},
+				map[string]interface{}{
+					"group_id":        float64(1),
+					"status":          "applied",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":        "20200101140000",
+							"comment":     "migration_3",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+						map[string]interface{}{
+							"name":        "20200101120000",
+							"comment":     "migration_1",
+							"status":      "applied",
+							"migrated_at": "2020-01-02T12:00:00Z",
+						},
+					},
+				},
 				map[string]interface{}{
-					"name":        "20200101120000",
-					"comment":     "_migration_3",
-					"migrated_at": "2020-01-02T13:00:00Z",
+					"group_id":        float64(0),
+					"status":          "pending",
+					"is_last_applied": false,
+					"migrations": []interface{}{
+						map[string]interface{}{
+							"name":         "20200101125000",
+							"comment":      "migration_4",
+							"status":       "pending",
+							"out_of_order": true,
+						},
+					},
 				},
 			},
 		}
@@ -0,0 +1,362 @@
+package asqlmessages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+
+	"github.com/a-novel-kit/quicklog"
+)
+
+// SchemaColumn describes a column of a table.
+type SchemaColumn struct {
+	Name     string
+	Type     string
+	Nullable bool
+	// Default is the SQL expression of the default value. It is empty if the column has no default.
+	Default string
+}
+
+// SchemaIndex describes an index of a table.
+type SchemaIndex struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
+	// Definition is the full CREATE INDEX statement.
+	Definition string
+}
+
+// SchemaForeignKey describes a foreign key constraint of a table.
+type SchemaForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+	OnUpdate          string
+	OnDelete          string
+}
+
+// SchemaConstraint describes a primary key, unique, check or exclusion constraint of a table.
+type SchemaConstraint struct {
+	Name string
+	Type string
+	// Definition is the SQL definition of the constraint (e.g. "CHECK (price > 0)").
+	Definition string
+}
+
+// SchemaTrigger describes a trigger of a table.
+type SchemaTrigger struct {
+	Name string
+	// Definition is the full CREATE TRIGGER statement.
+	Definition string
+}
+
+// SchemaTable describes the structure of a table.
+type SchemaTable struct {
+	Name        string
+	Columns     []SchemaColumn
+	Indexes     []SchemaIndex
+	ForeignKeys []SchemaForeignKey
+	Constraints []SchemaConstraint
+	Triggers    []SchemaTrigger
+}
+
+// SchemaEnum describes an enum type.
+type SchemaEnum struct {
+	Name   string
+	Values []string
+}
+
+// SchemaFunction describes a function or procedure. Procedures have no result.
+type SchemaFunction struct {
+	Name      string
+	Arguments string
+	Result    string
+	// Definition is the full CREATE FUNCTION statement.
+	Definition string
+}
+
+type schemaMessage struct {
+	schema    string
+	tables    []SchemaTable
+	enums     []SchemaEnum
+	functions []SchemaFunction
+
+	quicklog.Message
+}
+
+// Render rows as aligned columns. Empty cells at the end of a row are trimmed.
+func (schema *schemaMessage) alignRows(rows [][]string, indent string) []string {
+	var widths []int
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	output := make([]string, len(rows))
+
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = cell + strings.Repeat(" ", widths[j]-lipgloss.Width(cell))
+		}
+
+		output[i] = indent + strings.TrimRight(strings.Join(cells, "  "), " ")
+	}
+
+	return output
+}
+
+func (schema *schemaMessage) printColumns(table SchemaTable) []string {
+	rows := lo.Map(table.Columns, func(column SchemaColumn, _ int) []string {
+		row := []string{
+			column.Name,
+			lipgloss.NewStyle().Foreground(lipgloss.Color("33")).Render(column.Type),
+			lo.Ternary(column.Nullable, "NULL", "NOT NULL"),
+		}
+
+		if column.Default != "" {
+			row = append(row, lipgloss.NewStyle().Faint(true).Render("DEFAULT "+column.Default))
+		}
+
+		return row
+	})
+
+	return schema.alignRows(rows, "    ")
+}
+
+func (schema *schemaMessage) printIndexes(table SchemaTable) []string {
+	rows := lo.Map(table.Indexes, func(index SchemaIndex, _ int) []string {
+		kind := "INDEX"
+		if index.Primary {
+			kind = "PRIMARY KEY"
+		} else if index.Unique {
+			kind = "UNIQUE"
+		}
+
+		return []string{index.Name, kind + " (" + strings.Join(index.Columns, ", ") + ")"}
+	})
+
+	return schema.alignRows(rows, "    ")
+}
+
+func (schema *schemaMessage) printForeignKeys(table SchemaTable) []string {
+	rows := lo.Map(table.ForeignKeys, func(foreignKey SchemaForeignKey, _ int) []string {
+		return []string{
+			foreignKey.Name,
+			fmt.Sprintf(
+				"(%s) → %s(%s) ON UPDATE %s ON DELETE %s",
+				strings.Join(foreignKey.Columns, ", "),
+				foreignKey.ReferencedTable,
+				strings.Join(foreignKey.ReferencedColumns, ", "),
+				foreignKey.OnUpdate,
+				foreignKey.OnDelete,
+			),
+		}
+	})
+
+	return schema.alignRows(rows, "    ")
+}
+
+func (schema *schemaMessage) printConstraints(table SchemaTable) []string {
+	rows := lo.Map(table.Constraints, func(constraint SchemaConstraint, _ int) []string {
+		return []string{constraint.Name, constraint.Definition}
+	})
+
+	return schema.alignRows(rows, "    ")
+}
+
+func (schema *schemaMessage) printTriggers(table SchemaTable) []string {
+	rows := lo.Map(table.Triggers, func(trigger SchemaTrigger, _ int) []string {
+		return []string{trigger.Name, trigger.Definition}
+	})
+
+	return schema.alignRows(rows, "    ")
+}
+
+// Return the name of an object of the schema, qualified by the schema if there is one.
+func (schema *schemaMessage) qualify(name string) string {
+	return lo.Ternary(schema.schema == "", "", schema.schema+".") + name
+}
+
+func (schema *schemaMessage) printEnums() []string {
+	rows := lo.Map(schema.enums, func(enum SchemaEnum, _ int) []string {
+		return []string{schema.qualify(enum.Name), "(" + strings.Join(enum.Values, ", ") + ")"}
+	})
+
+	return schema.alignRows(rows, "  ")
+}
+
+func (schema *schemaMessage) printFunctions() []string {
+	rows := lo.Map(schema.functions, func(function SchemaFunction, _ int) []string {
+		return []string{
+			schema.qualify(function.Name) + "(" + function.Arguments + ")",
+			lo.Ternary(function.Result == "", "PROCEDURE", "RETURNS "+function.Result),
+		}
+	})
+
+	return schema.alignRows(rows, "  ")
+}
+
+func (schema *schemaMessage) empty() bool {
+	return len(schema.tables) == 0 && len(schema.enums) == 0 && len(schema.functions) == 0
+}
+
+func (schema *schemaMessage) RenderTerminal() string {
+	if schema.empty() {
+		return ""
+	}
+
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	sectionStyle := lipgloss.NewStyle().Faint(true)
+
+	var output []string
+
+	for _, table := range schema.tables {
+		output = append(output, titleStyle.Render(schema.qualify(table.Name)))
+
+		if len(table.Columns) > 0 {
+			output = append(output, sectionStyle.Render("  Columns"))
+			output = append(output, schema.printColumns(table)...)
+		}
+
+		if len(table.Indexes) > 0 {
+			output = append(output, sectionStyle.Render("  Indexes"))
+			output = append(output, schema.printIndexes(table)...)
+		}
+
+		if len(table.ForeignKeys) > 0 {
+			output = append(output, sectionStyle.Render("  Foreign keys"))
+			output = append(output, schema.printForeignKeys(table)...)
+		}
+
+		if len(table.Constraints) > 0 {
+			output = append(output, sectionStyle.Render("  Constraints"))
+			output = append(output, schema.printConstraints(table)...)
+		}
+
+		if len(table.Triggers) > 0 {
+			output = append(output, sectionStyle.Render("  Triggers"))
+			output = append(output, schema.printTriggers(table)...)
+		}
+	}
+
+	if len(schema.enums) > 0 {
+		output = append(output, titleStyle.Render("Enums"))
+		output = append(output, schema.printEnums()...)
+	}
+
+	if len(schema.functions) > 0 {
+		output = append(output, titleStyle.Render("Functions"))
+		output = append(output, schema.printFunctions()...)
+	}
+
+	return strings.Join(output, "\n") + "\n"
+}
+
+func (schema *schemaMessage) RenderJSON() map[string]interface{} {
+	if schema.empty() {
+		return nil
+	}
+
+	tables := lo.Map(schema.tables, func(table SchemaTable, _ int) interface{} {
+		return map[string]interface{}{
+			"name": table.Name,
+			"columns": lo.Map(table.Columns, func(column SchemaColumn, _ int) interface{} {
+				elem := map[string]interface{}{
+					"name":     column.Name,
+					"type":     column.Type,
+					"nullable": column.Nullable,
+				}
+
+				if column.Default != "" {
+					elem["default"] = column.Default
+				}
+
+				return elem
+			}),
+			"indexes": lo.Map(table.Indexes, func(index SchemaIndex, _ int) interface{} {
+				return map[string]interface{}{
+					"name":       index.Name,
+					"columns":    index.Columns,
+					"unique":     index.Unique,
+					"primary":    index.Primary,
+					"definition": index.Definition,
+				}
+			}),
+			"foreign_keys": lo.Map(table.ForeignKeys, func(foreignKey SchemaForeignKey, _ int) interface{} {
+				return map[string]interface{}{
+					"name":               foreignKey.Name,
+					"columns":            foreignKey.Columns,
+					"referenced_table":   foreignKey.ReferencedTable,
+					"referenced_columns": foreignKey.ReferencedColumns,
+					"on_update":          foreignKey.OnUpdate,
+					"on_delete":          foreignKey.OnDelete,
+				}
+			}),
+			"constraints": lo.Map(table.Constraints, func(constraint SchemaConstraint, _ int) interface{} {
+				return map[string]interface{}{
+					"name":       constraint.Name,
+					"type":       constraint.Type,
+					"definition": constraint.Definition,
+				}
+			}),
+			"triggers": lo.Map(table.Triggers, func(trigger SchemaTrigger, _ int) interface{} {
+				return map[string]interface{}{
+					"name":       trigger.Name,
+					"definition": trigger.Definition,
+				}
+			}),
+		}
+	})
+
+	enums := lo.Map(schema.enums, func(enum SchemaEnum, _ int) interface{} {
+		return map[string]interface{}{
+			"name":   enum.Name,
+			"values": enum.Values,
+		}
+	})
+
+	functions := lo.Map(schema.functions, func(function SchemaFunction, _ int) interface{} {
+		elem := map[string]interface{}{
+			"name":       function.Name,
+			"arguments":  function.Arguments,
+			"definition": function.Definition,
+		}
+
+		if function.Result != "" {
+			elem["result"] = function.Result
+		}
+
+		return elem
+	})
+
+	return map[string]interface{}{
+		"schema":    schema.schema,
+		"tables":    tables,
+		"enums":     enums,
+		"functions": functions,
+	}
+}
+
+// NewSchema renders the structure of a database schema: the columns, indexes, foreign keys, constraints and
+// triggers of its tables, followed by its enums and functions.
+func NewSchema(
+	schema string, tables []SchemaTable, enums []SchemaEnum, functions []SchemaFunction,
+) quicklog.Message {
+	return &schemaMessage{
+		schema:    schema,
+		tables:    tables,
+		enums:     enums,
+		functions: functions,
+	}
+}
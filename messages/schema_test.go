@@ -0,0 +1,197 @@
+package asqlmessages_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	asqlmessages "github.com/a-novel-kit/asql/messages"
+)
+
+func TestSchema(t *testing.T) {
+	t.Run("Render", func(t *testing.T) {
+		content := asqlmessages.NewSchema("public", []asqlmessages.SchemaTable{
+			{
+				Name: "table1",
+				Columns: []asqlmessages.SchemaColumn{
+					{Name: "id", Type: "integer", Default: "nextval('table1_id_seq'::regclass)"},
+					{Name: "name", Type: "character varying(255)", Nullable: true},
+				},
+				Indexes: []asqlmessages.SchemaIndex{
+					{
+						Name:       "table1_pkey",
+						Columns:    []string{"id"},
+						Unique:     true,
+						Primary:    true,
+						Definition: "CREATE UNIQUE INDEX table1_pkey ON public.table1 USING btree (id)",
+					},
+				},
+				Constraints: []asqlmessages.SchemaConstraint{
+					{Name: "table1_name_check", Type: "CHECK", Definition: "CHECK (name::text <> ''::text)"},
+					{Name: "table1_pkey", Type: "PRIMARY KEY", Definition: "PRIMARY KEY (id)"},
+				},
+				Triggers: []asqlmessages.SchemaTrigger{
+					{
+						Name: "table1_touch",
+						Definition: "CREATE TRIGGER table1_touch BEFORE UPDATE ON public.table1 " +
+							"FOR EACH ROW EXECUTE FUNCTION touch()",
+					},
+				},
+			},
+			{
+				Name: "table2",
+				Columns: []asqlmessages.SchemaColumn{
+					{Name: "table1_id", Type: "integer"},
+				},
+				ForeignKeys: []asqlmessages.SchemaForeignKey{
+					{
+						Name:              "table2_table1_id_fkey",
+						Columns:           []string{"table1_id"},
+						ReferencedTable:   "table1",
+						ReferencedColumns: []string{"id"},
+						OnUpdate:          "NO ACTION",
+						OnDelete:          "CASCADE",
+					},
+				},
+			},
+		}, []asqlmessages.SchemaEnum{
+			{Name: "role", Values: []string{"user", "admin"}},
+		}, []asqlmessages.SchemaFunction{
+			{
+				Name:       "touch",
+				Result:     "trigger",
+				Definition: "CREATE OR REPLACE FUNCTION public.touch() RETURNS trigger ...",
+			},
+			{
+				Name:       "archive",
+				Arguments:  "id integer",
+				Definition: "CREATE OR REPLACE PROCEDURE public.archive(IN id integer) ...",
+			},
+		})
+
+		expectConsole := "public.table1\n" +
+			"  Columns\n" +
+			"    id    integer                 NOT NULL  DEFAULT nextval('table1_id_seq'::regclass)\n" +
+			"    name  character varying(255)  NULL\n" +
+			"  Indexes\n" +
+			"    table1_pkey  PRIMARY KEY (id)\n" +
+			"  Constraints\n" +
+			"    table1_name_check  CHECK (name::text <> ''::text)\n" +
+			"    table1_pkey        PRIMARY KEY (id)\n" +
+			"  Triggers\n" +
+			"    table1_touch  CREATE TRIGGER table1_touch BEFORE UPDATE ON public.table1 " +
+			"FOR EACH ROW EXECUTE FUNCTION touch()\n" +
+			"public.table2\n" +
+			"  Columns\n" +
+			"    table1_id  integer  NOT NULL\n" +
+			"  Foreign keys\n" +
+			"    table2_table1_id_fkey  (table1_id) → table1(id) ON UPDATE NO ACTION ON DELETE CASCADE\n" +
+			"Enums\n" +
+			"  public.role  (user, admin)\n" +
+			"Functions\n" +
+			"  public.touch()              RETURNS trigger\n" +
+			"  public.archive(id integer)  PROCEDURE\n"
+		expectJSON := map[string]interface{}{
+			"schema": "public",
+			"tables": []interface{}{
+				map[string]interface{}{
+					"name": "table1",
+					"columns": []interface{}{
+						map[string]interface{}{
+							"name":     "id",
+							"type":     "integer",
+							"nullable": false,
+							"default":  "nextval('table1_id_seq'::regclass)",
+						},
+						map[string]interface{}{
+							"name":     "name",
+							"type":     "character varying(255)",
+							"nullable": true,
+						},
+					},
+					"indexes": []interface{}{
+						map[string]interface{}{
+							"name":       "table1_pkey",
+							"columns":    []string{"id"},
+							"unique":     true,
+							"primary":    true,
+							"definition": "CREATE UNIQUE INDEX table1_pkey ON public.table1 USING btree (id)",
+						},
+					},
+					"foreign_keys": []interface{}{},
+					"constraints": []interface{}{
+						map[string]interface{}{
+							"name":       "table1_name_check",
+							"type":       "CHECK",
+							"definition": "CHECK (name::text <> ''::text)",
+						},
+						map[string]interface{}{
+							"name":       "table1_pkey",
+							"type":       "PRIMARY KEY",
+							"definition": "PRIMARY KEY (id)",
+						},
+					},
+					"triggers": []interface{}{
+						map[string]interface{}{
+							"name": "table1_touch",
+							"definition": "CREATE TRIGGER table1_touch BEFORE UPDATE ON public.table1 " +
+								"FOR EACH ROW EXECUTE FUNCTION touch()",
+						},
+					},
+				},
+				map[string]interface{}{
+					"name": "table2",
+					"columns": []interface{}{
+						map[string]interface{}{
+							"name":     "table1_id",
+							"type":     "integer",
+							"nullable": false,
+						},
+					},
+					"indexes": []interface{}{},
+					"foreign_keys": []interface{}{
+						map[string]interface{}{
+							"name":               "table2_table1_id_fkey",
+							"columns":            []string{"table1_id"},
+							"referenced_table":   "table1",
+							"referenced_columns": []string{"id"},
+							"on_update":          "NO ACTION",
+							"on_delete":          "CASCADE",
+						},
+					},
+					"constraints": []interface{}{},
+					"triggers":    []interface{}{},
+				},
+			},
+			"enums": []interface{}{
+				map[string]interface{}{
+					"name":   "role",
+					"values": []string{"user", "admin"},
+				},
+			},
+			"functions": []interface{}{
+				map[string]interface{}{
+					"name":       "touch",
+					"arguments":  "",
+					"result":     "trigger",
+					"definition": "CREATE OR REPLACE FUNCTION public.touch() RETURNS trigger ...",
+				},
+				map[string]interface{}{
+					"name":       "archive",
+					"arguments":  "id integer",
+					"definition": "CREATE OR REPLACE PROCEDURE public.archive(IN id integer) ...",
+				},
+			},
+		}
+
+		require.Equal(t, expectConsole, content.RenderTerminal())
+		require.Equal(t, expectJSON, content.RenderJSON())
+	})
+
+	t.Run("NoTables", func(t *testing.T) {
+		content := asqlmessages.NewSchema("public", nil, nil, nil)
+
+		require.Equal(t, "", content.RenderTerminal())
+		require.Nil(t, content.RenderJSON())
+	})
+}
@@ -5,15 +5,13 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"io/fs"
+	"time"
 
-	"github.com/samber/lo"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/migrate"
 
 	"github.com/a-novel-kit/quicklog"
-	"github.com/a-novel-kit/quicklog/messages"
-
-	asqlmessages "github.com/a-novel-kit/asql/messages"
 )
 
 var (
@@ -21,61 +19,138 @@ var (
 	ErrCreateMigrator      = errors.New("failed to create migrator")
 	ErrApplyMigrations     = errors.New("failed to apply migrations")
 	ErrGetMigrationsStatus = errors.New("failed to get migrations status")
+	ErrInitMigrationAudits = errors.New("failed to init migration audits")
 )
 
 // Migrate looks for non-applied migrations, and applies them to the database.
-func Migrate(database *bun.DB, sqlMigrations embed.FS, logger quicklog.Logger) error {
-	loader := messages.NewLoader("discovering migrations...", &messages.LoaderConfigDefault)
-	clean := logger.LogAnimated(loader)
-	defer func() { go clean() }()
+func Migrate(database *bun.DB, sqlMigrations embed.FS, logger quicklog.Logger, opts ...MigrateOption) error {
+	_, err := MigrateWithSink(database, sqlMigrations, NewQuicklogMigrationSink(logger), opts...)
+	return err
+}
+
+// MigrateWithSink looks for non-applied migrations, and applies them to the database. Progress is reported to the
+// given sink, rather than to a quicklog.Logger.
+//
+// On success, it returns a summary of the run, that callers can use to react to schema changes.
+func MigrateWithSink(
+	database *bun.DB, sqlMigrations fs.FS, sink MigrationSink, opts ...MigrateOption,
+) (*MigrationResult, error) {
+	config := newMigrateConfig(opts)
+	result := new(MigrationResult)
 
 	// Discover existing migrations.
 	migrations := migrate.NewMigrations()
 	if err := migrations.Discover(sqlMigrations); err != nil {
-		loader.Error(ErrDiscoverMigrations)
-		return fmt.Errorf("discover migrations: %w", err)
+		sink.Failed(ErrDiscoverMigrations, err)
+		return nil, fmt.Errorf("discover migrations: %w", err)
 	}
-	loader.Update("migrations successfully discovered, applying migrations...")
 
-	migrator := migrate.NewMigrator(database, migrations)
+	// Bun assigns pending migrations to a new group, right after the last applied one. The value is known once the
+	// status of the migrations has been retrieved.
+	var groupID int64
+
+	migrator := migrate.NewMigrator(database, withAppliedEvents(migrations, sink, result, &groupID))
 	if err := migrator.Init(context.Background()); err != nil {
-		loader.Error(ErrCreateMigrator)
-		return fmt.Errorf("create migrator: %w", err)
+		sink.Failed(ErrCreateMigrator, err)
+		return nil, fmt.Errorf("create migrator: %w", err)
+	}
+
+	if err := initMigrationAudits(context.Background(), database); err != nil {
+		sink.Failed(ErrInitMigrationAudits, err)
+		return nil, fmt.Errorf("init migration audits: %w", err)
+	}
+
+	status, err := migrator.MigrationsWithStatus(context.Background())
+	if err != nil {
+		sink.Failed(ErrGetMigrationsStatus, err)
+		return nil, fmt.Errorf("get migrations status: %w", err)
+	}
+
+	groupID = status.LastGroupID() + 1
+	sink.Started(status.Unapplied())
+
+	// Bun applies out-of-order migrations silently, so we need to look for them beforehand.
+	if outOfOrder := findOutOfOrderMigrations(status); len(outOfOrder) > 0 {
+		switch config.outOfOrderPolicy {
+		case OutOfOrderFail:
+			err = &OutOfOrderMigrationsError{Migrations: outOfOrder, Status: status}
+			sink.Failed(ErrOutOfOrderMigrations, err)
+			return nil, fmt.Errorf("check migrations order: %w", err)
+		case OutOfOrderWarn:
+			sink.OutOfOrder(outOfOrder)
+		case OutOfOrderAllow:
+		}
+
+		result.OutOfOrder = outOfOrder
 	}
 
 	// Run migrations.
+	start := time.Now()
 	migrated, err := migrator.Migrate(context.Background())
 	if err != nil {
-		loader.Error(ErrApplyMigrations)
-		return fmt.Errorf("apply migrations: %w", err)
+		// Attach the source of the failing migration, so it can be displayed.
+		var migrationErr *MigrationError
+		if errors.As(err, &migrationErr) {
+			migrationErr.File, migrationErr.SQL = loadMigrationFile(sqlMigrations, migrationErr.Migration)
+		}
+
+		sink.Failed(ErrApplyMigrations, err)
+		return nil, fmt.Errorf("apply migrations: %w", err)
+	}
+
+	result.Duration = time.Since(start)
+
+	if migrated != nil && len(migrated.Migrations) > 0 {
+		result.GroupID = migrated.ID
+
+		// Migrations are already committed at this point, so failing to record their audit does not fail the run.
+		if err = recordMigrationAudit(context.Background(), database, migrated, result.Duration, config); err != nil {
+			result.AuditErr = errors.Join(ErrRecordMigrationAudit, err)
+		}
+
+		sink.GroupCommitted(migrated)
 	}
 
 	applied, err := migrator.MigrationsWithStatus(context.Background())
 	if err != nil {
-		loader.Error(ErrGetMigrationsStatus)
-		return fmt.Errorf("get migrations status: %w", err)
+		sink.Failed(ErrGetMigrationsStatus, err)
+		return nil, fmt.Errorf("get migrations status: %w", err)
 	}
 
-	hasNewMigrations := migrated != nil && len(migrated.Migrations) > 0
-	migrationsSubTitle := lo.TernaryF(
-		hasNewMigrations,
-		func() string {
-			return fmt.Sprintf("%v new migrations applied in group %v", len(migrated.Migrations), migrated.ID)
-		},
-		func() string {
-			return "No new migrations applied"
-		},
-	)
-
-	loader.Nest(
-		messages.NewTitle(
-			"Migrations applied",
-			migrationsSubTitle,
-			asqlmessages.NewMigrations(applied, migrated.ID),
-		),
-	)
-	loader.Success("migrations successfully applied.")
+	result.Status = applied
+	sink.Finished(applied)
 
 	// Great success.
-	return nil
+	return result, nil
+}
+
+// Wrap the up function of every migration, so the sink is notified each time one of them is successfully applied.
+// Applied migrations are also recorded in the result.
+func withAppliedEvents(
+	migrations *migrate.Migrations, sink MigrationSink, result *MigrationResult, groupID *int64,
+) *migrate.Migrations {
+	wrapped := migrate.NewMigrations()
+
+	for _, migration := range migrations.Sorted() {
+		if up := migration.Up; up != nil {
+			migration.Up = func(ctx context.Context, database *bun.DB) error {
+				start := time.Now()
+				if err := up(ctx, database); err != nil {
+					return &MigrationError{Migration: migration, Err: err}
+				}
+
+				applied := AppliedMigration{Migration: migration, Duration: time.Since(start)}
+				applied.GroupID = *groupID
+
+				result.Applied = append(result.Applied, applied)
+				sink.MigrationApplied(applied.Migration, applied.Duration)
+
+				return nil
+			}
+		}
+
+		wrapped.Add(migration)
+	}
+
+	return wrapped
 }
@@ -0,0 +1,106 @@
+package asql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+)
+
+var ErrRecordMigrationAudit = errors.New("failed to record migration audit")
+
+// MigrationAudit holds the metadata recorded alongside each group of migrations applied by MigrateWithSink. It is
+// stored in a companion table of the bun migrations table.
+type MigrationAudit struct {
+	bun.BaseModel `bun:"table:asql_migration_audits"`
+
+	// GroupID is the ID of the migration group the audit refers to.
+	GroupID int64 `bun:"group_id,pk" json:"group_id"`
+	// Migrations lists the names of the migrations applied in the group.
+	Migrations []string `bun:"migrations,array" json:"migrations"`
+
+	// Hostname is the name of the host that applied the migrations.
+	Hostname string `bun:"hostname" json:"hostname,omitempty"`
+	// AppVersion is the version of the application that applied the migrations, if provided.
+	AppVersion string `bun:"app_version" json:"app_version,omitempty"`
+	// GitSHA is the commit of the application that applied the migrations, if provided.
+	GitSHA string `bun:"git_sha" json:"git_sha,omitempty"`
+	// Operator is the person, or system, that applied the migrations. It defaults to the current OS user.
+	Operator string `bun:"operator" json:"operator,omitempty"`
+
+	// Duration is the time it took to apply the whole group.
+	Duration time.Duration `bun:"duration" json:"duration"`
+	// AppliedAt is the time the group was committed.
+	AppliedAt time.Time `bun:"applied_at,notnull,nullzero,default:current_timestamp" json:"applied_at"`
+}
+
+// WithAppVersion sets the application version recorded in the migration audit.
+func WithAppVersion(version string) MigrateOption {
+	return func(config *migrateConfig) {
+		config.appVersion = version
+	}
+}
+
+// WithGitSHA sets the commit recorded in the migration audit.
+func WithGitSHA(sha string) MigrateOption {
+	return func(config *migrateConfig) {
+		config.gitSHA = sha
+	}
+}
+
+// WithOperator sets the operator recorded in the migration audit. It defaults to the current OS user.
+func WithOperator(operator string) MigrateOption {
+	return func(config *migrateConfig) {
+		config.operator = operator
+	}
+}
+
+// Create the audit table, if it does not exist yet.
+func initMigrationAudits(ctx context.Context, database bun.IDB) error {
+	_, err := database.NewCreateTable().Model((*MigrationAudit)(nil)).IfNotExists().Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("create audit table: %w", err)
+	}
+
+	return nil
+}
+
+// Save the audit of a newly committed migration group.
+func recordMigrationAudit(
+	ctx context.Context, database bun.IDB, group *migrate.MigrationGroup, duration time.Duration, config *migrateConfig,
+) error {
+	// Errors are ignored: missing information should not prevent the audit from being recorded.
+	hostname, _ := os.Hostname()
+
+	operator := config.operator
+	if operator == "" {
+		if current, err := user.Current(); err == nil {
+			operator = current.Username
+		}
+	}
+
+	audit := &MigrationAudit{
+		GroupID:    group.ID,
+		Migrations: make([]string, len(group.Migrations)),
+		Hostname:   hostname,
+		AppVersion: config.appVersion,
+		GitSHA:     config.gitSHA,
+		Operator:   operator,
+		Duration:   duration,
+	}
+
+	for i, migration := range group.Migrations {
+		audit.Migrations[i] = migration.String()
+	}
+
+	if _, err := database.NewInsert().Model(audit).Exec(ctx); err != nil {
+		return fmt.Errorf("insert audit: %w", err)
+	}
+
+	return nil
+}
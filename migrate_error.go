@@ -0,0 +1,90 @@
+package asql
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"github.com/uptrace/bun/migrate"
+
+	asqlmessages "github.com/a-novel-kit/asql/messages"
+)
+
+// MigrationError is returned when a migration cannot be applied. It points to the file that failed.
+type MigrationError struct {
+	Migration migrate.Migration
+	// File is the path of the up migration file, relative to the migrations file system. It is empty if the file
+	// could not be found.
+	File string
+	// SQL is the content of File.
+	SQL string
+
+	Err error
+}
+
+func (err *MigrationError) Error() string {
+	return "migration " + err.Migration.String() + ": " + err.Err.Error()
+}
+
+func (err *MigrationError) Unwrap() error {
+	return err.Err
+}
+
+// Failure describes the error for rendering. Postgres fields are filled when the error comes from the database.
+//
+// When the migration is split into multiple statements (using the --bun:split directive), the error position is
+// relative to the failing statement, and the SQL context may be inaccurate.
+func (err *MigrationError) Failure() asqlmessages.MigrationFailure {
+	failure := asqlmessages.MigrationFailure{
+		File: lo.CoalesceOrEmpty(err.File, err.Migration.String()),
+		SQL:  err.SQL,
+		Err:  err.Err,
+	}
+
+	var pgErr pgdriver.Error
+	if errors.As(err.Err, &pgErr) {
+		failure.Code = pgErr.Field('C')
+		failure.Message = pgErr.Field('M')
+		failure.Detail = pgErr.Field('D')
+		failure.Hint = pgErr.Field('H')
+		// Ignore invalid positions, the snippet is optional.
+		failure.Position, _ = strconv.Atoi(pgErr.Field('P'))
+	}
+
+	return failure
+}
+
+// Look for the up file of a migration, and load its content.
+func loadMigrationFile(sqlMigrations fs.FS, migration migrate.Migration) (string, string) {
+	prefix := migration.String() + "."
+
+	var (
+		file    string
+		content []byte
+	)
+
+	_ = fs.WalkDir(sqlMigrations, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		fileName := path.Base(filePath)
+		if !strings.HasPrefix(fileName, prefix) || !strings.HasSuffix(fileName, ".up.sql") {
+			return nil
+		}
+
+		file = filePath
+		content, err = fs.ReadFile(sqlMigrations, filePath)
+		if err != nil {
+			return err
+		}
+
+		return fs.SkipAll
+	})
+
+	return file, string(content)
+}
@@ -0,0 +1,58 @@
+package asql_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun/migrate"
+
+	"github.com/a-novel-kit/asql"
+	asqlmessages "github.com/a-novel-kit/asql/messages"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestMigrationError(t *testing.T) {
+	errFoo := errors.New("foo")
+
+	err := &asql.MigrationError{
+		Migration: migrate.Migration{Name: "20200101120000", Comment: "migration_1"},
+		Err:       errFoo,
+	}
+
+	require.ErrorIs(t, err, errFoo)
+	require.Equal(t, "migration 20200101120000_migration_1: foo", err.Error())
+	require.Equal(t, asqlmessages.MigrationFailure{
+		File: "20200101120000_migration_1",
+		Err:  errFoo,
+	}, err.Failure())
+}
+
+func TestMigrateWithSinkError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	db := asqltest.NewDB(t, asqltest.WithIsolation())
+
+	sqlMigrations := fstest.MapFS{
+		"migrations/20200101120000_broken.up.sql": &fstest.MapFile{
+			Data: []byte("CREATE TABLE broken (\n    id SERIAL PRIMARY KEY,\n    name VARCHAR(255) NOT NULLL\n);\n"),
+		},
+		"migrations/20200101120000_broken.down.sql": &fstest.MapFile{
+			Data: []byte("DROP TABLE broken;\n"),
+		},
+	}
+
+	_, err := asql.MigrateWithSink(db, sqlMigrations, asql.NewNopMigrationSink())
+	require.Error(t, err)
+
+	var migrationErr *asql.MigrationError
+	require.ErrorAs(t, err, &migrationErr)
+	require.Equal(t, "migrations/20200101120000_broken.up.sql", migrationErr.File)
+
+	failure := migrationErr.Failure()
+	require.Equal(t, "42601", failure.Code)
+	require.NotZero(t, failure.Position)
+}
@@ -0,0 +1,351 @@
+package asql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+	"github.com/uptrace/bun/schema"
+)
+
+var (
+	ErrGenerateMigration = errors.New("failed to generate migration")
+	// ErrNoSchemaChanges is returned by GenerateMigration when the models match the database schema.
+	ErrNoSchemaChanges = errors.New("models match the database schema")
+)
+
+// Header of the generated files. Generated migrations are drafts: some changes cannot be detected (column
+// renames are seen as a drop and an add), or reverted automatically (dropped tables).
+const generatedMigrationHeader = "-- Draft generated from bun models. Review it before applying.\n\n"
+
+type generateMigrationConfig struct {
+	schemaName    string
+	excludeTables []string
+	dropUnknown   bool
+	indexes       []MigrationIndex
+}
+
+// MigrationIndex is an index expected on the table of a model. Bun models only declare unique constraints, so
+// other indexes are given to GenerateMigration with WithIndexes.
+type MigrationIndex struct {
+	Name  string
+	Table string
+	// Columns are the indexed columns, in order.
+	Columns []string
+	Unique  bool
+}
+
+// GenerateMigrationOption customizes the behavior of GenerateMigration.
+type GenerateMigrationOption func(config *generateMigrationConfig)
+
+// WithGenerateSchemaName sets the database schema compared with the models. Defaults to "public".
+func WithGenerateSchemaName(schemaName string) GenerateMigrationOption {
+	return func(config *generateMigrationConfig) {
+		config.schemaName = schemaName
+	}
+}
+
+// WithGenerateExcludedTables ignores the given tables, on both the model and the database side.
+func WithGenerateExcludedTables(tables ...string) GenerateMigrationOption {
+	return func(config *generateMigrationConfig) {
+		config.excludeTables = append(config.excludeTables, tables...)
+	}
+}
+
+// WithDropUnknownTables drops the tables of the database that have no model. By default, they are ignored, so
+// models can be compared with the database a few at a time.
+func WithDropUnknownTables() GenerateMigrationOption {
+	return func(config *generateMigrationConfig) {
+		config.dropUnknown = true
+	}
+}
+
+// WithIndexes declares the indexes expected on the tables of the models. Indexes are compared by name: missing
+// indexes are created, and indexes whose columns differ are recreated.
+//
+// The declared indexes are the full list for their table: other indexes of that table are dropped, except those
+// that back a constraint. Indexes of tables without declared indexes are left untouched.
+func WithIndexes(indexes ...MigrationIndex) GenerateMigrationOption {
+	return func(config *generateMigrationConfig) {
+		config.indexes = append(config.indexes, indexes...)
+	}
+}
+
+// GeneratedMigration describes the files written by GenerateMigration.
+type GeneratedMigration struct {
+	// Name of the migration, made of a timestamp and the comment (e.g. "20240101120000_add_users").
+	Name string
+
+	UpPath   string
+	DownPath string
+
+	Up   string
+	Down string
+}
+
+var migrationCommentRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Tables that belong to asql, or to the bun migrator, are never part of the comparison.
+var internalTables = []string{"bun_migrations", "bun_migration_locks", "asql_migration_audits"}
+
+// Return the names of the tables of the models. Models are registered in a table cache of their own, so the
+// database cache is not affected.
+func modelTables(database *bun.DB, models []any) (map[string]bool, error) {
+	tables := schema.NewTables(database.Dialect())
+	output := make(map[string]bool, len(models))
+
+	for i, model := range models {
+		typ := reflect.TypeOf(model)
+		for typ != nil && typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+
+		if typ == nil || typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("model %d: expected a struct or a pointer to a struct, got %T", i, model)
+		}
+
+		output[tables.Get(typ).Name] = true
+	}
+
+	return output, nil
+}
+
+func validateIndex(index MigrationIndex) error {
+	switch {
+	case index.Name == "":
+		return errors.New("index has no name")
+	case index.Table == "":
+		return fmt.Errorf("index %s has no table", index.Name)
+	case len(index.Columns) == 0:
+		return fmt.Errorf("index %s has no column", index.Name)
+	}
+
+	return nil
+}
+
+// Format a statement of the migration.
+func statementSQL(database *bun.DB, query schema.QueryAppender) (string, error) {
+	statement, err := query.AppendQuery(database.Formatter(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(statement) + ";", nil
+}
+
+func createIndexSQL(database *bun.DB, schemaName string, index MigrationIndex) (string, error) {
+	query := database.NewCreateIndex().
+		Index(index.Name).
+		TableExpr("?.?", bun.Ident(schemaName), bun.Ident(index.Table)).
+		Column(index.Columns...)
+	if index.Unique {
+		query = query.Unique()
+	}
+
+	return statementSQL(database, query)
+}
+
+func dropIndexSQL(database *bun.DB, schemaName string, name string) (string, error) {
+	return statementSQL(database, database.NewDropIndex().Index("?.?", bun.Ident(schemaName), bun.Ident(name)))
+}
+
+// Compare the declared indexes with the indexes of the database. Statements are returned in the order they must
+// run, for both directions.
+func diffIndexes(
+	database *bun.DB, current *Schema, indexes []MigrationIndex,
+) (up []string, down []string, err error) {
+	declared := make(map[string][]MigrationIndex)
+	tables := make([]string, 0)
+
+	for _, index := range indexes {
+		if err = validateIndex(index); err != nil {
+			return nil, nil, err
+		}
+
+		if _, ok := declared[index.Table]; !ok {
+			tables = append(tables, index.Table)
+		}
+
+		declared[index.Table] = append(declared[index.Table], index)
+	}
+
+	for _, tableName := range tables {
+		existing := make(map[string]SchemaIndex)
+
+		if table, ok := current.Table(tableName); ok {
+			constraints := make(map[string]bool, len(table.Constraints))
+			for _, constraint := range table.Constraints {
+				constraints[constraint.Name] = true
+			}
+
+			for _, index := range table.Indexes {
+				if !index.Primary && !constraints[index.Name] {
+					existing[index.Name] = index
+				}
+			}
+		}
+
+		for _, index := range declared[tableName] {
+			actual, ok := existing[index.Name]
+			delete(existing, index.Name)
+
+			if ok && actual.Unique == index.Unique && slices.Equal(actual.Columns, index.Columns) {
+				continue
+			}
+
+			if ok {
+				dropSQL, err := dropIndexSQL(database, current.Name, index.Name)
+				if err != nil {
+					return nil, nil, fmt.Errorf("drop index %s: %w", index.Name, err)
+				}
+
+				up = append(up, dropSQL)
+				down = append(down, actual.Definition+";")
+			}
+
+			createSQL, err := createIndexSQL(database, current.Name, index)
+			if err != nil {
+				return nil, nil, fmt.Errorf("create index %s: %w", index.Name, err)
+			}
+
+			dropSQL, err := dropIndexSQL(database, current.Name, index.Name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("drop index %s: %w", index.Name, err)
+			}
+
+			up = append(up, createSQL)
+			down = append(down, dropSQL)
+		}
+
+		// Remaining indexes are not declared.
+		for _, name := range slices.Sorted(maps.Keys(existing)) {
+			dropSQL, err := dropIndexSQL(database, current.Name, name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("drop index %s: %w", name, err)
+			}
+
+			up = append(up, dropSQL)
+			down = append(down, existing[name].Definition+";")
+		}
+	}
+
+	// Changes are reverted in reverse order.
+	slices.Reverse(down)
+
+	return up, down, nil
+}
+
+// GenerateMigration compares the given bun models with the database schema, and writes a draft migration pair
+// ("<timestamp>_<comment>.up.sql" and ".down.sql") that brings the database in line with the models, into the
+// directory.
+//
+// Generated statements cover new tables, added or dropped columns, column type and nullability changes, unique
+// constraints and foreign keys. Bun models cannot declare other indexes: pass them with WithIndexes to have them
+// created, changed or dropped as well.
+//
+// If the models already match the database, no file is written, and ErrNoSchemaChanges is returned.
+func GenerateMigration(
+	ctx context.Context,
+	database *bun.DB,
+	directory string,
+	comment string,
+	models []any,
+	opts ...GenerateMigrationOption,
+) (*GeneratedMigration, error) {
+	config := &generateMigrationConfig{schemaName: database.Dialect().DefaultSchema()}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	tables, err := modelTables(database, models)
+	if err != nil {
+		return nil, errors.Join(ErrGenerateMigration, err)
+	}
+
+	current, err := Inspect(ctx, database, config.schemaName)
+	if err != nil {
+		return nil, errors.Join(ErrGenerateMigration, fmt.Errorf("inspect schema: %w", err))
+	}
+
+	indexesUp, indexesDown, err := diffIndexes(database, current, config.indexes)
+	if err != nil {
+		return nil, errors.Join(ErrGenerateMigration, err)
+	}
+
+	excludeTables := append(append([]string{}, internalTables...), config.excludeTables...)
+
+	if !config.dropUnknown {
+		for _, table := range current.Tables {
+			if !tables[table.Name] {
+				excludeTables = append(excludeTables, table.Name)
+			}
+		}
+	}
+
+	// The auto migrator writes its files in a directory of its own, that are then renamed after the conventions
+	// of this package.
+	draftDirectory, err := os.MkdirTemp("", "asql-migration-")
+	if err != nil {
+		return nil, errors.Join(ErrGenerateMigration, fmt.Errorf("create draft directory: %w", err))
+	}
+	defer os.RemoveAll(draftDirectory)
+
+	autoMigrator, err := migrate.NewAutoMigrator(
+		database,
+		migrate.WithModel(models...),
+		migrate.WithSchemaName(config.schemaName),
+		migrate.WithExcludeTable(excludeTables...),
+		migrate.WithMigrationsDirectoryAuto(draftDirectory),
+	)
+	if err != nil {
+		return nil, errors.Join(ErrGenerateMigration, fmt.Errorf("create auto migrator: %w", err))
+	}
+
+	files, err := autoMigrator.CreateTxSQLMigrations(ctx)
+	if err != nil {
+		return nil, errors.Join(ErrGenerateMigration, err)
+	}
+
+	// Files are returned in order: up, then down. Indexes are created once their table exists, and dropped before
+	// it is.
+	up := strings.TrimSpace(strings.Join(append([]string{strings.TrimSpace(files[0].Content)}, indexesUp...), "\n"))
+	down := strings.TrimSpace(strings.Join(append(indexesDown, strings.TrimSpace(files[1].Content)), "\n"))
+
+	if up == "" {
+		return nil, ErrNoSchemaChanges
+	}
+
+	slug := strings.Trim(migrationCommentRegexp.ReplaceAllString(strings.ToLower(comment), "_"), "_")
+	if slug == "" {
+		slug = "generated"
+	}
+
+	generated := &GeneratedMigration{
+		Name: time.Now().UTC().Format("20060102150405") + "_" + slug,
+		Up:   generatedMigrationHeader + up + "\n",
+		Down: generatedMigrationHeader + down + "\n",
+	}
+
+	generated.UpPath = filepath.Join(directory, generated.Name+".up.sql")
+	generated.DownPath = filepath.Join(directory, generated.Name+".down.sql")
+
+	if err = os.WriteFile(generated.UpPath, []byte(generated.Up), 0o644); err != nil {
+		return nil, errors.Join(ErrGenerateMigration, fmt.Errorf("write up migration: %w", err))
+	}
+
+	if err = os.WriteFile(generated.DownPath, []byte(generated.Down), 0o644); err != nil {
+		return nil, errors.Join(ErrGenerateMigration, fmt.Errorf("write down migration: %w", err))
+	}
+
+	return generated, nil
+}
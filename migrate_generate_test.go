@@ -0,0 +1,104 @@
+package asql_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+
+	"github.com/a-novel-kit/asql"
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+type generatedTableModel struct {
+	bun.BaseModel `bun:"generated_table"`
+
+	ID    int64  `bun:"id,pk"`
+	Label string `bun:"label,notnull,unique"`
+}
+
+func TestGenerateMigration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	db := asqltest.NewDB(t, asqltest.WithIsolation(), asqltest.WithMigrations(databasemocks.MigrationsAll))
+
+	ctx := context.Background()
+	directory := t.TempDir()
+
+	generated, err := asql.GenerateMigration(
+		ctx, db, directory, "Add generated table!", []any{(*generatedTableModel)(nil)},
+	)
+	require.NoError(t, err)
+
+	require.Regexp(t, `^\d{14}_add_generated_table$`, generated.Name)
+	require.Regexp(t, `CREATE TABLE ("public"\.)?"generated_table"`, generated.Up)
+	require.Regexp(t, `DROP TABLE ("public"\.)?"generated_table"`, generated.Down)
+	// Tables without a model are left untouched.
+	require.NotContains(t, generated.Up, "table1")
+
+	up, err := os.ReadFile(generated.UpPath)
+	require.NoError(t, err)
+	require.Equal(t, generated.Up, string(up))
+
+	down, err := os.ReadFile(generated.DownPath)
+	require.NoError(t, err)
+	require.Equal(t, generated.Down, string(down))
+
+	// Once applied, the models match the schema.
+	_, err = db.Exec(generated.Up)
+	require.NoError(t, err)
+
+	_, err = asql.GenerateMigration(ctx, db, directory, "noop", []any{(*generatedTableModel)(nil)})
+	require.ErrorIs(t, err, asql.ErrNoSchemaChanges)
+
+	// Indexes are declared apart from the models.
+	index := asql.MigrationIndex{
+		Name: "generated_label_idx", Table: "generated_table", Columns: []string{"id", "label"},
+	}
+
+	generated, err = asql.GenerateMigration(
+		ctx, db, directory, "index", []any{(*generatedTableModel)(nil)}, asql.WithIndexes(index),
+	)
+	require.NoError(t, err)
+	require.Contains(
+		t, generated.Up, `CREATE INDEX "generated_label_idx" ON "public"."generated_table" ("id", "label");`,
+	)
+	require.Contains(t, generated.Down, `DROP INDEX "public"."generated_label_idx";`)
+
+	_, err = db.Exec(generated.Up)
+	require.NoError(t, err)
+
+	_, err = asql.GenerateMigration(
+		ctx, db, directory, "noop", []any{(*generatedTableModel)(nil)}, asql.WithIndexes(index),
+	)
+	require.ErrorIs(t, err, asql.ErrNoSchemaChanges)
+
+	// Changing the columns of an index recreates it, and the down migration restores the original.
+	index.Columns = []string{"label"}
+
+	generated, err = asql.GenerateMigration(
+		ctx, db, directory, "index", []any{(*generatedTableModel)(nil)}, asql.WithIndexes(index),
+	)
+	require.NoError(t, err)
+	require.Contains(t, generated.Up, `DROP INDEX "public"."generated_label_idx";`)
+	require.Contains(t, generated.Up, `("label");`)
+	require.Contains(
+		t, generated.Down, "CREATE INDEX generated_label_idx ON public.generated_table USING btree (id, label);",
+	)
+}
+
+func TestGenerateMigrationInvalidModels(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	for _, model := range []any{nil, 42, new(string)} {
+		_, err := asql.GenerateMigration(context.Background(), db, t.TempDir(), "invalid", []any{model})
+		require.ErrorIs(t, err, asql.ErrGenerateMigration)
+	}
+}
@@ -0,0 +1,174 @@
+package asql
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+)
+
+// MigrationHistoryItem is a single migration, as recorded in the bun migrations table.
+type MigrationHistoryItem struct {
+	Name       string    `json:"name"`
+	MigratedAt time.Time `json:"migrated_at"`
+}
+
+// MigrationHistoryGroup is a group of applied migrations, along with the audit recorded when it was committed.
+type MigrationHistoryGroup struct {
+	GroupID    int64                  `json:"group_id"`
+	Migrations []MigrationHistoryItem `json:"migrations"`
+	// Audit is nil for groups that were applied without asql, or before audits were recorded.
+	Audit *MigrationAudit `json:"audit,omitempty"`
+}
+
+// MigrationHistory is the full history of the migrations applied to a database, most recent group first.
+type MigrationHistory struct {
+	Groups []MigrationHistoryGroup `json:"groups"`
+}
+
+// Name of the bun migrations table. Migrators of this package use the default.
+const bunMigrationsTable = "bun_migrations"
+
+const tableExistsQuery = `SELECT pg_catalog.to_regclass(?) IS NOT NULL`
+
+func tableExists(ctx context.Context, database bun.IDB, table string) (bool, error) {
+	var exists bool
+	if err := database.NewRaw(tableExistsQuery, table).Scan(ctx, &exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// LoadMigrationHistory reads the history of every migration applied to the database, along with their audit
+// metadata.
+//
+// It only reads from the database, so it works with read-only roles. Databases that were never migrated have an
+// empty history, and groups applied before audits were introduced have no audit.
+func LoadMigrationHistory(ctx context.Context, database *bun.DB) (*MigrationHistory, error) {
+	history := &MigrationHistory{Groups: make([]MigrationHistoryGroup, 0)}
+
+	exists, err := tableExists(ctx, database, bunMigrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("look for migrations table: %w", err)
+	}
+
+	if !exists {
+		return history, nil
+	}
+
+	// An empty set of migrations is enough to read the applied migrations, regardless of the files they come from.
+	applied, err := migrate.NewMigrator(database, migrate.NewMigrations()).AppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+
+	exists, err = tableExists(ctx, database, database.Table(reflect.TypeFor[MigrationAudit]()).Name)
+	if err != nil {
+		return nil, fmt.Errorf("look for audits table: %w", err)
+	}
+
+	var audits []*MigrationAudit
+	if exists {
+		if err = database.NewSelect().Model(&audits).Scan(ctx); err != nil {
+			return nil, fmt.Errorf("list audits: %w", err)
+		}
+	}
+
+	auditsMap := make(map[int64]*MigrationAudit, len(audits))
+	for _, audit := range audits {
+		auditsMap[audit.GroupID] = audit
+	}
+
+	groupsIndexes := make(map[int64]int)
+
+	// Applied migrations are sorted by name. Restore the order they were applied in.
+	slices.SortStableFunc(applied, func(migrationA, migrationB migrate.Migration) int {
+		if migrationA.GroupID != migrationB.GroupID {
+			return cmp.Compare(migrationA.GroupID, migrationB.GroupID)
+		}
+
+		return strings.Compare(migrationA.Name, migrationB.Name)
+	})
+
+	for _, migration := range applied {
+		index, ok := groupsIndexes[migration.GroupID]
+		if !ok {
+			index = len(history.Groups)
+			groupsIndexes[migration.GroupID] = index
+			history.Groups = append(history.Groups, MigrationHistoryGroup{
+				GroupID: migration.GroupID,
+				Audit:   auditsMap[migration.GroupID],
+			})
+		}
+
+		history.Groups[index].Migrations = append(history.Groups[index].Migrations, MigrationHistoryItem{
+			Name:       migration.Name,
+			MigratedAt: migration.MigratedAt,
+		})
+	}
+
+	// Most recent group first.
+	slices.Reverse(history.Groups)
+
+	return history, nil
+}
+
+// JSON exports the history as indented JSON.
+func (history *MigrationHistory) JSON() ([]byte, error) {
+	return json.MarshalIndent(history, "", "  ")
+}
+
+// Markdown exports the history as a Markdown document, with one section per group.
+func (history *MigrationHistory) Markdown() string {
+	var builder strings.Builder
+
+	builder.WriteString("# Migration history\n")
+
+	if len(history.Groups) == 0 {
+		builder.WriteString("\nNo migration applied.\n")
+		return builder.String()
+	}
+
+	for _, group := range history.Groups {
+		builder.WriteString(fmt.Sprintf("\n## Group %v\n\n", group.GroupID))
+
+		if group.Audit != nil {
+			builder.WriteString("| Applied at | Duration | Hostname | Version | Git SHA | Operator |\n")
+			builder.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+			builder.WriteString(fmt.Sprintf(
+				"| %s | %s | %s | %s | %s | %s |\n\n",
+				group.Audit.AppliedAt.Format(time.RFC3339),
+				group.Audit.Duration,
+				markdownCell(group.Audit.Hostname),
+				markdownCell(group.Audit.AppVersion),
+				markdownCell(group.Audit.GitSHA),
+				markdownCell(group.Audit.Operator),
+			))
+		}
+
+		for _, migration := range group.Migrations {
+			builder.WriteString(fmt.Sprintf(
+				"- `%s` (%s)\n", migration.Name, migration.MigratedAt.Format(time.RFC3339),
+			))
+		}
+	}
+
+	return builder.String()
+}
+
+// Format a value for a Markdown table cell.
+func markdownCell(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return strings.ReplaceAll(value, "|", "\\|")
+}
@@ -0,0 +1,176 @@
+package asql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/asql"
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestMigrationHistoryExport(t *testing.T) {
+	history := &asql.MigrationHistory{
+		Groups: []asql.MigrationHistoryGroup{
+			{
+				GroupID: 2,
+				Migrations: []asql.MigrationHistoryItem{
+					{Name: "20200101130000", MigratedAt: time.Date(2020, 1, 2, 13, 0, 0, 0, time.UTC)},
+				},
+				Audit: &asql.MigrationAudit{
+					GroupID:    2,
+					Migrations: []string{"20200101130000_migration_2"},
+					Hostname:   "host",
+					AppVersion: "v1.0.0",
+					GitSHA:     "abcdef",
+					Operator:   "ci|bot",
+					Duration:   time.Second,
+					AppliedAt:  time.Date(2020, 1, 2, 13, 0, 0, 0, time.UTC),
+				},
+			},
+			{
+				GroupID: 1,
+				Migrations: []asql.MigrationHistoryItem{
+					{Name: "20200101120000", MigratedAt: time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)},
+				},
+			},
+		},
+	}
+
+	t.Run("Markdown", func(t *testing.T) {
+		expect := "# Migration history\n" +
+			"\n## Group 2\n\n" +
+			"| Applied at | Duration | Hostname | Version | Git SHA | Operator |\n" +
+			"| --- | --- | --- | --- | --- | --- |\n" +
+			"| 2020-01-02T13:00:00Z | 1s | host | v1.0.0 | abcdef | ci\\|bot |\n\n" +
+			"- `20200101130000` (2020-01-02T13:00:00Z)\n" +
+			"\n## Group 1\n\n" +
+			"- `20200101120000` (2020-01-02T12:00:00Z)\n"
+
+		require.Equal(t, expect, history.Markdown())
+	})
+
+	t.Run("MarkdownEmpty", func(t *testing.T) {
+		require.Equal(t, "# Migration history\n\nNo migration applied.\n", new(asql.MigrationHistory).Markdown())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		output, err := history.JSON()
+		require.NoError(t, err)
+
+		require.JSONEq(t, `{
+			"groups": [
+				{
+					"group_id": 2,
+					"migrations": [{"name": "20200101130000", "migrated_at": "2020-01-02T13:00:00Z"}],
+					"audit": {
+						"group_id": 2,
+						"migrations": ["20200101130000_migration_2"],
+						"hostname": "host",
+						"app_version": "v1.0.0",
+						"git_sha": "abcdef",
+						"operator": "ci|bot",
+						"duration": 1000000000,
+						"applied_at": "2020-01-02T13:00:00Z"
+					}
+				},
+				{
+					"group_id": 1,
+					"migrations": [{"name": "20200101120000", "migrated_at": "2020-01-02T12:00:00Z"}]
+				}
+			]
+		}`, string(output))
+	})
+}
+
+func TestLoadMigrationHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	db := asqltest.NewDB(t, asqltest.WithIsolation())
+
+	_, err := asql.MigrateWithSink(
+		db, databasemocks.MigrationsGroup1, asql.NewNopMigrationSink(),
+		asql.WithAppVersion("v1.0.0"), asql.WithGitSHA("abcdef"), asql.WithOperator("ci"),
+	)
+	require.NoError(t, err)
+
+	_, err = asql.MigrateWithSink(db, databasemocks.MigrationsAll, asql.NewNopMigrationSink())
+	require.NoError(t, err)
+
+	history, err := asql.LoadMigrationHistory(context.Background(), db)
+	require.NoError(t, err)
+
+	require.Len(t, history.Groups, 2)
+
+	require.Equal(t, int64(2), history.Groups[0].GroupID)
+	require.Len(t, history.Groups[0].Migrations, 1)
+	require.Equal(t, "20200101140000", history.Groups[0].Migrations[0].Name)
+	require.NotNil(t, history.Groups[0].Audit)
+	require.Equal(t, []string{"20200101140000_migration_3"}, history.Groups[0].Audit.Migrations)
+
+	require.Equal(t, int64(1), history.Groups[1].GroupID)
+	require.Len(t, history.Groups[1].Migrations, 2)
+	require.NotNil(t, history.Groups[1].Audit)
+	require.Equal(t, "v1.0.0", history.Groups[1].Audit.AppVersion)
+	require.Equal(t, "abcdef", history.Groups[1].Audit.GitSHA)
+	require.Equal(t, "ci", history.Groups[1].Audit.Operator)
+	require.NotEmpty(t, history.Groups[1].Audit.Hostname)
+}
+
+func TestLoadMigrationHistoryWithoutTables(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	db := asqltest.NewDB(t, asqltest.WithIsolation())
+
+	// A database that was never migrated has an empty history.
+	history, err := asql.LoadMigrationHistory(context.Background(), db)
+	require.NoError(t, err)
+	require.Empty(t, history.Groups)
+
+	// Loading the history does not create any table.
+	var tables []string
+	require.NoError(t, db.NewRaw(
+		"SELECT tablename FROM pg_catalog.pg_tables WHERE schemaname = 'public'",
+	).Scan(context.Background(), &tables))
+	require.Empty(t, tables)
+
+	// Migrations applied before audits were introduced have no audit.
+	_, err = asql.MigrateWithSink(db, databasemocks.MigrationsGroup1, asql.NewNopMigrationSink())
+	require.NoError(t, err)
+
+	_, err = db.Exec("DROP TABLE asql_migration_audits")
+	require.NoError(t, err)
+
+	history, err = asql.LoadMigrationHistory(context.Background(), db)
+	require.NoError(t, err)
+	require.Len(t, history.Groups, 1)
+	require.Nil(t, history.Groups[0].Audit)
+}
+
+func TestMigrateWithSinkAuditFailure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	db := asqltest.NewDB(t, asqltest.WithIsolation())
+
+	_, err := asql.MigrateWithSink(db, databasemocks.MigrationsGroup1, asql.NewNopMigrationSink())
+	require.NoError(t, err)
+
+	// Prevent the next audit from being recorded.
+	_, err = db.Exec("ALTER TABLE asql_migration_audits ADD CONSTRAINT no_more_audits CHECK (group_id < 2)")
+	require.NoError(t, err)
+
+	// The migrations are applied nonetheless, and the failure is reported in the result.
+	result, err := asql.MigrateWithSink(db, databasemocks.MigrationsAll, asql.NewNopMigrationSink())
+	require.NoError(t, err)
+	require.Equal(t, []string{"20200101140000_migration_3"}, result.AppliedNames())
+	require.ErrorIs(t, result.AuditErr, asql.ErrRecordMigrationAudit)
+}
@@ -0,0 +1,32 @@
+package asql
+
+type migrateConfig struct {
+	outOfOrderPolicy OutOfOrderPolicy
+
+	// Audit metadata.
+	appVersion string
+	gitSHA     string
+	operator   string
+}
+
+// MigrateOption customizes the behavior of Migrate and MigrateWithSink.
+type MigrateOption func(config *migrateConfig)
+
+// WithOutOfOrderPolicy sets the policy applied to out-of-order migrations. It defaults to OutOfOrderWarn.
+func WithOutOfOrderPolicy(policy OutOfOrderPolicy) MigrateOption {
+	return func(config *migrateConfig) {
+		config.outOfOrderPolicy = policy
+	}
+}
+
+func newMigrateConfig(opts []MigrateOption) *migrateConfig {
+	config := &migrateConfig{
+		outOfOrderPolicy: OutOfOrderWarn,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return config
+}
@@ -0,0 +1,53 @@
+package asql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun/migrate"
+)
+
+var ErrOutOfOrderMigrations = errors.New("found out-of-order migrations")
+
+// OutOfOrderPolicy controls how MigrateWithSink reacts to pending migrations that are older than the last applied
+// one. This usually happens when 2 branches, each with its own migrations, are merged.
+type OutOfOrderPolicy string
+
+const (
+	// OutOfOrderAllow applies out-of-order migrations silently, in a new group.
+	OutOfOrderAllow OutOfOrderPolicy = "allow"
+	// OutOfOrderWarn applies out-of-order migrations in a new group, and reports them to the sink.
+	OutOfOrderWarn OutOfOrderPolicy = "warn"
+	// OutOfOrderFail refuses to apply any migration as long as out-of-order migrations are pending.
+	OutOfOrderFail OutOfOrderPolicy = "fail"
+)
+
+// OutOfOrderMigrationsError is returned when out-of-order migrations are found, under the OutOfOrderFail policy.
+type OutOfOrderMigrationsError struct {
+	// Migrations lists the pending migrations that are older than the last applied one.
+	Migrations migrate.MigrationSlice
+	// Status holds every known migration, with its status at the time of the check.
+	Status migrate.MigrationSlice
+}
+
+func (err *OutOfOrderMigrationsError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrOutOfOrderMigrations.Error(), err.Migrations.String())
+}
+
+func (err *OutOfOrderMigrationsError) Unwrap() error {
+	return ErrOutOfOrderMigrations
+}
+
+// Return the pending migrations that are older than the most recent applied migration.
+func findOutOfOrderMigrations(status migrate.MigrationSlice) migrate.MigrationSlice {
+	// Applied migrations are sorted in descending order, so the first one is the most recent.
+	applied := status.Applied()
+	if len(applied) == 0 {
+		return nil
+	}
+
+	return lo.Filter(status.Unapplied(), func(item migrate.Migration, _ int) bool {
+		return item.Name < applied[0].Name
+	})
+}
@@ -0,0 +1,47 @@
+package asql
+
+import (
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun/migrate"
+)
+
+// AppliedMigration is a migration that was applied during a call to MigrateWithSink.
+type AppliedMigration struct {
+	migrate.Migration
+
+	// Duration is the time it took to run the migration.
+	Duration time.Duration
+}
+
+// MigrationResult describes the outcome of a successful call to MigrateWithSink.
+type MigrationResult struct {
+	// GroupID is the ID of the group the new migrations were applied in. It is 0 if no migration was applied.
+	GroupID int64
+	// Applied lists the migrations applied during this run, in the order they were applied.
+	Applied []AppliedMigration
+	// OutOfOrder lists the migrations that were applied, while being older than a previously applied migration.
+	OutOfOrder migrate.MigrationSlice
+	// Duration is the total time spent applying migrations.
+	Duration time.Duration
+	// Status holds every known migration, with its status after the run, in ascending order.
+	Status migrate.MigrationSlice
+	// AuditErr is set when the migrations were applied, but their audit could not be recorded. It wraps
+	// ErrRecordMigrationAudit.
+	AuditErr error
+}
+
+// HasNewMigrations returns true if at least one migration was applied during the run. Callers can use it to
+// decide whether the schema changed.
+func (result *MigrationResult) HasNewMigrations() bool {
+	return len(result.Applied) > 0
+}
+
+// AppliedNames returns the names of the migrations applied during the run, as they appear in the migration files
+// (without extension).
+func (result *MigrationResult) AppliedNames() []string {
+	return lo.Map(result.Applied, func(item AppliedMigration, _ int) string {
+		return item.String()
+	})
+}
@@ -0,0 +1,39 @@
+package asql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun/migrate"
+
+	"github.com/a-novel-kit/asql"
+)
+
+func TestMigrationResult(t *testing.T) {
+	t.Run("NoMigrations", func(t *testing.T) {
+		result := &asql.MigrationResult{}
+
+		require.False(t, result.HasNewMigrations())
+		require.Empty(t, result.AppliedNames())
+	})
+
+	t.Run("WithMigrations", func(t *testing.T) {
+		result := &asql.MigrationResult{
+			GroupID: 2,
+			Applied: []asql.AppliedMigration{
+				{
+					Migration: migrate.Migration{Name: "20200101120000", Comment: "migration_1", GroupID: 2},
+					Duration:  time.Second,
+				},
+				{
+					Migration: migrate.Migration{Name: "20200101130000", Comment: "migration_2", GroupID: 2},
+					Duration:  time.Second,
+				},
+			},
+		}
+
+		require.True(t, result.HasNewMigrations())
+		require.Equal(t, []string{"20200101120000_migration_1", "20200101130000_migration_2"}, result.AppliedNames())
+	})
+}
@@ -0,0 +1,232 @@
+package asql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun/migrate"
+
+	"github.com/a-novel-kit/quicklog"
+	"github.com/a-novel-kit/quicklog/messages"
+
+	asqlmessages "github.com/a-novel-kit/asql/messages"
+)
+
+// MigrationSink receives the events emitted while migrations are applied. It allows Migrate to report its progress
+// to any destination, from an interactive terminal to a structured log pipeline.
+//
+// Events are emitted sequentially, from the goroutine that runs the migrations. Once Failed or Finished has been
+// called, no other event is emitted.
+type MigrationSink interface {
+	// Started is called once migrations have been discovered, with the list of migrations that are about to be
+	// applied. The list is empty when the database is already up-to-date.
+	Started(pending migrate.MigrationSlice)
+	// OutOfOrder is called before pending migrations are applied, with the ones that are older than the most
+	// recent applied migration. It is only called under the OutOfOrderWarn policy.
+	OutOfOrder(migrations migrate.MigrationSlice)
+	// MigrationApplied is called every time a migration is successfully applied, along with the time it took to
+	// run.
+	MigrationApplied(migration migrate.Migration, duration time.Duration)
+	// GroupCommitted is called once every pending migration has been applied. It is not called if no migration
+	// was pending.
+	GroupCommitted(group *migrate.MigrationGroup)
+	// Failed is called when the process stops on an error. The reason is one of the sentinel errors of this
+	// package, while err holds the underlying cause.
+	Failed(reason error, err error)
+	// Finished is called after a successful run, with the status of every known migration.
+	Finished(status migrate.MigrationSlice)
+}
+
+type quicklogMigrationSink struct {
+	logger quicklog.Logger
+
+	loader messages.Loader
+	clean  func()
+
+	// The group committed during this run, if any. It is used to highlight the new migrations.
+	committed *migrate.MigrationGroup
+	// The migrations reported as out of order during this run, if any.
+	outOfOrder migrate.MigrationSlice
+}
+
+// Start the animated loader, if not already running.
+func (sink *quicklogMigrationSink) getOrStartLoader(step string) messages.Loader {
+	if sink.loader == nil {
+		sink.loader = messages.NewLoader(step, &messages.LoaderConfigDefault)
+		sink.clean = sink.logger.LogAnimated(sink.loader)
+	}
+
+	return sink.loader
+}
+
+// Release the animated loader. Cleaning is done in the background, so it does not block the caller.
+func (sink *quicklogMigrationSink) close() {
+	if sink.clean != nil {
+		go sink.clean()
+	}
+}
+
+func (sink *quicklogMigrationSink) Started(pending migrate.MigrationSlice) {
+	sink.getOrStartLoader(fmt.Sprintf("migrations successfully discovered, applying %v migrations...", len(pending)))
+}
+
+func (sink *quicklogMigrationSink) OutOfOrder(migrations migrate.MigrationSlice) {
+	sink.outOfOrder = migrations
+	sink.getOrStartLoader("").Update(fmt.Sprintf("applying %v out-of-order migrations...", len(migrations)))
+}
+
+func (sink *quicklogMigrationSink) MigrationApplied(migration migrate.Migration, _ time.Duration) {
+	sink.getOrStartLoader("").Update(fmt.Sprintf("migration %s applied...", migration.String()))
+}
+
+func (sink *quicklogMigrationSink) GroupCommitted(group *migrate.MigrationGroup) {
+	sink.committed = group
+}
+
+func (sink *quicklogMigrationSink) Failed(reason error, err error) {
+	loader := sink.getOrStartLoader("")
+
+	// Show the offending migrations, so the operator knows which files to fix.
+	var outOfOrderErr *OutOfOrderMigrationsError
+	if errors.As(err, &outOfOrderErr) {
+		loader.Nest(asqlmessages.NewMigrations(
+			outOfOrderErr.Status, 0, asqlmessages.WithOutOfOrder(outOfOrderErr.Migrations...),
+		))
+	}
+
+	// Show the failing migration, along with the SQL context of the error.
+	var migrationErr *MigrationError
+	if errors.As(err, &migrationErr) {
+		loader.Nest(asqlmessages.NewMigrationError(migrationErr.Failure()))
+	}
+
+	loader.Error(reason)
+	sink.close()
+}
+
+func (sink *quicklogMigrationSink) Finished(status migrate.MigrationSlice) {
+	loader := sink.getOrStartLoader("")
+
+	var lastAppliedGroup int64
+	if sink.committed != nil {
+		lastAppliedGroup = sink.committed.ID
+	}
+
+	migrationsSubTitle := lo.TernaryF(
+		lastAppliedGroup > 0,
+		func() string {
+			return fmt.Sprintf("%v new migrations applied in group %v", len(sink.committed.Migrations), lastAppliedGroup)
+		},
+		func() string {
+			return "No new migrations applied"
+		},
+	)
+
+	loader.Nest(
+		messages.NewTitle(
+			"Migrations applied",
+			migrationsSubTitle,
+			asqlmessages.NewMigrations(status, lastAppliedGroup, asqlmessages.WithOutOfOrder(sink.outOfOrder...)),
+		),
+	)
+	loader.Success("migrations successfully applied.")
+	sink.close()
+}
+
+// NewQuicklogMigrationSink renders migration events as an animated quicklog message, followed by a summary of
+// every known migration.
+//
+// The sink is meant for a single run of migrations.
+func NewQuicklogMigrationSink(logger quicklog.Logger) MigrationSink {
+	return &quicklogMigrationSink{logger: logger}
+}
+
+type slogMigrationSink struct {
+	logger *slog.Logger
+}
+
+func (sink *slogMigrationSink) Started(pending migrate.MigrationSlice) {
+	sink.logger.LogAttrs(
+		context.Background(), slog.LevelInfo, "migrations started",
+		slog.Int("pending", len(pending)),
+	)
+}
+
+func (sink *slogMigrationSink) OutOfOrder(migrations migrate.MigrationSlice) {
+	sink.logger.LogAttrs(
+		context.Background(), slog.LevelWarn, "out-of-order migrations",
+		slog.Any("migrations", lo.Map(migrations, func(item migrate.Migration, _ int) string {
+			return item.String()
+		})),
+	)
+}
+
+func (sink *slogMigrationSink) MigrationApplied(migration migrate.Migration, duration time.Duration) {
+	sink.logger.LogAttrs(
+		context.Background(), slog.LevelInfo, "migration applied",
+		slog.String("name", migration.Name),
+		slog.String("comment", migration.Comment),
+		slog.Int64("group_id", migration.GroupID),
+		slog.Duration("duration", duration),
+	)
+}
+
+func (sink *slogMigrationSink) GroupCommitted(group *migrate.MigrationGroup) {
+	sink.logger.LogAttrs(
+		context.Background(), slog.LevelInfo, "migration group committed",
+		slog.Int64("group_id", group.ID),
+		slog.Int("migrations", len(group.Migrations)),
+	)
+}
+
+func (sink *slogMigrationSink) Failed(reason error, err error) {
+	attrs := []slog.Attr{
+		slog.String("reason", reason.Error()),
+		slog.String("error", err.Error()),
+	}
+
+	var migrationErr *MigrationError
+	if errors.As(err, &migrationErr) {
+		failure := migrationErr.Failure()
+		attrs = append(attrs, slog.String("file", failure.File))
+
+		if failure.Code != "" {
+			attrs = append(attrs, slog.String("sqlstate", failure.Code))
+		}
+	}
+
+	sink.logger.LogAttrs(context.Background(), slog.LevelError, "migrations failed", attrs...)
+}
+
+func (sink *slogMigrationSink) Finished(status migrate.MigrationSlice) {
+	sink.logger.LogAttrs(
+		context.Background(), slog.LevelInfo, "migrations finished",
+		slog.Int("applied", len(status.Applied())),
+		slog.Int("pending", len(status.Unapplied())),
+		slog.Int64("last_group_id", status.LastGroupID()),
+	)
+}
+
+// NewSlogMigrationSink emits migration events as structured log records, through the given handler.
+func NewSlogMigrationSink(handler slog.Handler) MigrationSink {
+	return &slogMigrationSink{logger: slog.New(handler)}
+}
+
+type nopMigrationSink struct{}
+
+func (nopMigrationSink) Started(_ migrate.MigrationSlice)                      {}
+func (nopMigrationSink) OutOfOrder(_ migrate.MigrationSlice)                   {}
+func (nopMigrationSink) MigrationApplied(_ migrate.Migration, _ time.Duration) {}
+func (nopMigrationSink) GroupCommitted(_ *migrate.MigrationGroup)              {}
+func (nopMigrationSink) Failed(_ error, _ error)                               {}
+func (nopMigrationSink) Finished(_ migrate.MigrationSlice)                     {}
+
+// NewNopMigrationSink discards every migration event. Use it for headless jobs that only care about the returned
+// error.
+func NewNopMigrationSink() MigrationSink {
+	return nopMigrationSink{}
+}
@@ -0,0 +1,204 @@
+package asql_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+
+	"github.com/a-novel-kit/asql"
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+type recordingSink struct {
+	events []string
+}
+
+func (sink *recordingSink) Started(pending migrate.MigrationSlice) {
+	sink.events = append(sink.events, "started:"+pending.String())
+}
+
+func (sink *recordingSink) OutOfOrder(migrations migrate.MigrationSlice) {
+	sink.events = append(sink.events, "out_of_order:"+migrations.String())
+}
+
+func (sink *recordingSink) MigrationApplied(migration migrate.Migration, _ time.Duration) {
+	sink.events = append(sink.events, "applied:"+migration.String())
+}
+
+func (sink *recordingSink) GroupCommitted(group *migrate.MigrationGroup) {
+	sink.events = append(sink.events, "committed:"+group.String())
+}
+
+func (sink *recordingSink) Failed(reason error, _ error) {
+	sink.events = append(sink.events, "failed:"+reason.Error())
+}
+
+func (sink *recordingSink) Finished(status migrate.MigrationSlice) {
+	sink.events = append(sink.events, "finished:"+status.String())
+}
+
+func TestSlogMigrationSink(t *testing.T) {
+	var buffer bytes.Buffer
+
+	sink := asql.NewSlogMigrationSink(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			// Remove time for reproducible outputs.
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return attr
+		},
+	}))
+
+	migration := migrate.Migration{Name: "20200101120000", Comment: "migration_1", GroupID: 1}
+
+	sink.Started(migrate.MigrationSlice{migration})
+	sink.OutOfOrder(migrate.MigrationSlice{migration})
+	sink.MigrationApplied(migration, time.Second)
+	sink.GroupCommitted(&migrate.MigrationGroup{ID: 1, Migrations: migrate.MigrationSlice{migration}})
+	sink.Failed(asql.ErrApplyMigrations, errors.New("uh oh"))
+	sink.Finished(migrate.MigrationSlice{{ID: 1, Name: "20200101120000", GroupID: 1}, {Name: "20200101130000"}})
+
+	var records []map[string]interface{}
+
+	decoder := json.NewDecoder(&buffer)
+	for decoder.More() {
+		var record map[string]interface{}
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+
+	require.Equal(t, []map[string]interface{}{
+		{"level": "INFO", "msg": "migrations started", "pending": float64(1)},
+		{
+			"level":      "WARN",
+			"msg":        "out-of-order migrations",
+			"migrations": []interface{}{"20200101120000_migration_1"},
+		},
+		{
+			"level":    "INFO",
+			"msg":      "migration applied",
+			"name":     "20200101120000",
+			"comment":  "migration_1",
+			"group_id": float64(1),
+			"duration": float64(time.Second),
+		},
+		{"level": "INFO", "msg": "migration group committed", "group_id": float64(1), "migrations": float64(1)},
+		{"level": "ERROR", "msg": "migrations failed", "reason": "failed to apply migrations", "error": "uh oh"},
+		{"level": "INFO", "msg": "migrations finished", "applied": float64(1), "pending": float64(1), "last_group_id": float64(1)},
+	}, records)
+}
+
+func TestMigrateWithSink(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	db := asqltest.NewDB(t, asqltest.WithIsolation())
+
+	sink := new(recordingSink)
+	result, err := asql.MigrateWithSink(db, databasemocks.MigrationsGroup1, sink)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"started:20200101120000_migration_1, 20200101130000_migration_2",
+		"applied:20200101120000_migration_1",
+		"applied:20200101130000_migration_2",
+		"committed:group #1 (20200101120000_migration_1, 20200101130000_migration_2)",
+		"finished:20200101120000_migration_1, 20200101130000_migration_2",
+	}, sink.events)
+
+	require.Equal(t, int64(1), result.GroupID)
+	require.Equal(t, []string{"20200101120000_migration_1", "20200101130000_migration_2"}, result.AppliedNames())
+	require.Len(t, result.Status, 2)
+
+	// Running again should not apply anything.
+	sink = new(recordingSink)
+	result, err = asql.MigrateWithSink(db, databasemocks.MigrationsGroup1, sink)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"started:empty",
+		"finished:20200101120000_migration_1, 20200101130000_migration_2",
+	}, sink.events)
+
+	require.False(t, result.HasNewMigrations())
+	require.Equal(t, int64(0), result.GroupID)
+	require.Len(t, result.Status, 2)
+}
+
+func TestMigrateWithSinkOutOfOrder(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	// Each policy starts from a database where the second migration was skipped.
+	newDB := func(t *testing.T) *bun.DB {
+		t.Helper()
+
+		return asqltest.NewDB(t, asqltest.WithIsolation(), asqltest.WithMigrations(databasemocks.MigrationsSkip2))
+	}
+
+	t.Run("Fail", func(t *testing.T) {
+		db := newDB(t)
+
+		sink := new(recordingSink)
+		_, err := asql.MigrateWithSink(
+			db, databasemocks.MigrationsAll, sink, asql.WithOutOfOrderPolicy(asql.OutOfOrderFail),
+		)
+		require.ErrorIs(t, err, asql.ErrOutOfOrderMigrations)
+
+		var outOfOrderErr *asql.OutOfOrderMigrationsError
+		require.ErrorAs(t, err, &outOfOrderErr)
+		require.Equal(t, "20200101130000_migration_2", outOfOrderErr.Migrations.String())
+
+		require.Equal(t, []string{
+			"started:20200101130000_migration_2",
+			"failed:found out-of-order migrations",
+		}, sink.events)
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		db := newDB(t)
+
+		sink := new(recordingSink)
+		result, err := asql.MigrateWithSink(db, databasemocks.MigrationsAll, sink)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{
+			"started:20200101130000_migration_2",
+			"out_of_order:20200101130000_migration_2",
+			"applied:20200101130000_migration_2",
+			"committed:group #2 (20200101130000_migration_2)",
+			"finished:20200101120000_migration_1, 20200101130000_migration_2, 20200101140000_migration_3",
+		}, sink.events)
+		require.Equal(t, "20200101130000_migration_2", result.OutOfOrder.String())
+	})
+
+	t.Run("Allow", func(t *testing.T) {
+		db := newDB(t)
+
+		sink := new(recordingSink)
+		result, err := asql.MigrateWithSink(
+			db, databasemocks.MigrationsAll, sink, asql.WithOutOfOrderPolicy(asql.OutOfOrderAllow),
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{
+			"started:20200101130000_migration_2",
+			"applied:20200101130000_migration_2",
+			"committed:group #2 (20200101130000_migration_2)",
+			"finished:20200101120000_migration_1, 20200101130000_migration_2, 20200101140000_migration_3",
+		}, sink.events)
+		require.Equal(t, "20200101130000_migration_2", result.OutOfOrder.String())
+	})
+}
@@ -0,0 +1,165 @@
+package asql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaChangeKind tells how an object differs between two snapshots.
+type SchemaChangeKind string
+
+const (
+	SchemaChangeAdded   SchemaChangeKind = "added"
+	SchemaChangeRemoved SchemaChangeKind = "removed"
+	SchemaChangeChanged SchemaChangeKind = "changed"
+)
+
+// SchemaObjectType is the type of object a SchemaChange applies to.
+type SchemaObjectType string
+
+const (
+	SchemaObjectSchema     SchemaObjectType = "schema"
+	SchemaObjectTable      SchemaObjectType = "table"
+	SchemaObjectColumn     SchemaObjectType = "column"
+	SchemaObjectIndex      SchemaObjectType = "index"
+	SchemaObjectForeignKey SchemaObjectType = "foreign key"
+	SchemaObjectConstraint SchemaObjectType = "constraint"
+	SchemaObjectTrigger    SchemaObjectType = "trigger"
+	SchemaObjectEnum       SchemaObjectType = "enum"
+	SchemaObjectFunction   SchemaObjectType = "function"
+)
+
+// SchemaChange is a difference between two snapshots.
+type SchemaChange struct {
+	Kind   SchemaChangeKind `json:"kind"`
+	Object SchemaObjectType `json:"object"`
+	// Path identifies the object, from its schema (e.g. "public.users.email" for a column).
+	Path string `json:"path"`
+	// Before is the object in the first snapshot. It is nil for added objects.
+	Before any `json:"before,omitempty"`
+	// After is the object in the second snapshot. It is nil for removed objects.
+	After any `json:"after,omitempty"`
+}
+
+func (change SchemaChange) String() string {
+	return fmt.Sprintf("%s %s %s", change.Kind, change.Object, change.Path)
+}
+
+// SchemaDiff lists the differences between two snapshots.
+type SchemaDiff struct {
+	Changes []SchemaChange `json:"changes"`
+}
+
+// Empty returns true if the snapshots describe the same structure.
+func (diff *SchemaDiff) Empty() bool {
+	return len(diff.Changes) == 0
+}
+
+// String returns a summary of the changes, one per line.
+func (diff *SchemaDiff) String() string {
+	lines := make([]string, len(diff.Changes))
+	for i, change := range diff.Changes {
+		lines[i] = change.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Compare two lists of objects, identified by key. Objects present in both lists are compared with nested, if
+// given, to report changes on their children. Otherwise, they are compared as a whole.
+func diffObjects[T any](
+	diff *SchemaDiff,
+	object SchemaObjectType,
+	prefix string,
+	before, after []T,
+	key func(T) string,
+	nested func(path string, before, after T),
+) {
+	afterByKey := make(map[string]T, len(after))
+	for _, item := range after {
+		afterByKey[key(item)] = item
+	}
+
+	beforeKeys := make(map[string]bool, len(before))
+
+	for _, beforeItem := range before {
+		itemKey := key(beforeItem)
+		beforeKeys[itemKey] = true
+
+		afterItem, ok := afterByKey[itemKey]
+
+		switch {
+		case !ok:
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind: SchemaChangeRemoved, Object: object, Path: prefix + itemKey, Before: beforeItem,
+			})
+		case nested != nil:
+			nested(prefix+itemKey, beforeItem, afterItem)
+		case !reflect.DeepEqual(beforeItem, afterItem):
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind: SchemaChangeChanged, Object: object, Path: prefix + itemKey, Before: beforeItem, After: afterItem,
+			})
+		}
+	}
+
+	for _, afterItem := range after {
+		if itemKey := key(afterItem); !beforeKeys[itemKey] {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind: SchemaChangeAdded, Object: object, Path: prefix + itemKey, After: afterItem,
+			})
+		}
+	}
+}
+
+func (diff *SchemaDiff) diffTables(path string, before, after SchemaTable) {
+	prefix := path + "."
+
+	diffObjects(diff, SchemaObjectColumn, prefix, before.Columns, after.Columns, func(column SchemaColumn) string {
+		return column.Name
+	}, nil)
+	diffObjects(diff, SchemaObjectIndex, prefix, before.Indexes, after.Indexes, func(index SchemaIndex) string {
+		return index.Name
+	}, nil)
+	diffObjects(diff, SchemaObjectForeignKey, prefix, before.ForeignKeys, after.ForeignKeys,
+		func(foreignKey SchemaForeignKey) string {
+			return foreignKey.Name
+		}, nil)
+	diffObjects(diff, SchemaObjectConstraint, prefix, before.Constraints, after.Constraints,
+		func(constraint SchemaConstraint) string {
+			return constraint.Name
+		}, nil)
+	diffObjects(diff, SchemaObjectTrigger, prefix, before.Triggers, after.Triggers, func(trigger SchemaTrigger) string {
+		return trigger.Name
+	}, nil)
+}
+
+func (diff *SchemaDiff) diffSchemas(path string, before, after *Schema) {
+	prefix := path + "."
+
+	diffObjects(diff, SchemaObjectTable, prefix, before.Tables, after.Tables, func(table SchemaTable) string {
+		return table.Name
+	}, diff.diffTables)
+	diffObjects(diff, SchemaObjectEnum, prefix, before.Enums, after.Enums, func(enum SchemaEnum) string {
+		return enum.Name
+	}, nil)
+	// Functions can be overloaded, so they are identified by their arguments as well.
+	diffObjects(diff, SchemaObjectFunction, prefix, before.Functions, after.Functions,
+		func(function SchemaFunction) string {
+			return function.Name + "(" + function.Arguments + ")"
+		}, nil)
+}
+
+// DiffSchemas lists the differences between two snapshots: objects that were added to b, removed from a, or that
+// changed between them. Changes are listed schema by schema, then table by table.
+//
+// Column order is not compared, since columns cannot be reordered without recreating the table.
+func DiffSchemas(a, b *SchemaSnapshot) *SchemaDiff {
+	diff := &SchemaDiff{Changes: make([]SchemaChange, 0)}
+
+	diffObjects(diff, SchemaObjectSchema, "", a.Schemas, b.Schemas, func(schema *Schema) string {
+		return schema.Name
+	}, diff.diffSchemas)
+
+	return diff
+}
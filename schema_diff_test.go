@@ -0,0 +1,170 @@
+package asql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/asql"
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	before := &asql.SchemaSnapshot{
+		Schemas: []*asql.Schema{
+			{
+				Name: "public",
+				Tables: []asql.SchemaTable{
+					{
+						Name: "users",
+						Columns: []asql.SchemaColumn{
+							{Name: "id", Type: "integer"},
+							{Name: "name", Type: "character varying(255)"},
+							{Name: "legacy", Type: "text", Nullable: true},
+						},
+						Constraints: []asql.SchemaConstraint{
+							{Name: "users_pkey", Type: "PRIMARY KEY", Definition: "PRIMARY KEY (id)"},
+						},
+					},
+					{Name: "sessions"},
+				},
+				Enums: []asql.SchemaEnum{{Name: "role", Values: []string{"user", "admin"}}},
+				Functions: []asql.SchemaFunction{
+					{Name: "touch", Arguments: "", Result: "trigger", Definition: "v1"},
+				},
+			},
+			{Name: "legacy"},
+		},
+	}
+
+	after := &asql.SchemaSnapshot{
+		Schemas: []*asql.Schema{
+			{
+				Name: "public",
+				Tables: []asql.SchemaTable{
+					{
+						Name: "users",
+						Columns: []asql.SchemaColumn{
+							{Name: "email", Type: "text"},
+							{Name: "id", Type: "integer"},
+							{Name: "name", Type: "text"},
+						},
+						Constraints: []asql.SchemaConstraint{
+							{Name: "users_pkey", Type: "PRIMARY KEY", Definition: "PRIMARY KEY (id)"},
+						},
+					},
+					{Name: "posts"},
+				},
+				Enums: []asql.SchemaEnum{{Name: "role", Values: []string{"user", "admin", "owner"}}},
+				Functions: []asql.SchemaFunction{
+					{Name: "touch", Arguments: "", Result: "trigger", Definition: "v1"},
+					{Name: "touch", Arguments: "at timestamp with time zone", Result: "trigger", Definition: "v1"},
+				},
+			},
+		},
+	}
+
+	t.Run("Identical", func(t *testing.T) {
+		require.True(t, asql.DiffSchemas(before, before).Empty())
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		diff := asql.DiffSchemas(before, after)
+		require.False(t, diff.Empty())
+
+		require.Equal(t, `changed column public.users.name
+removed column public.users.legacy
+added column public.users.email
+removed table public.sessions
+added table public.posts
+changed enum public.role
+added function public.touch(at timestamp with time zone)
+removed schema legacy`, diff.String())
+
+		require.Equal(t, asql.SchemaChange{
+			Kind:   asql.SchemaChangeChanged,
+			Object: asql.SchemaObjectColumn,
+			Path:   "public.users.name",
+			Before: asql.SchemaColumn{Name: "name", Type: "character varying(255)"},
+			After:  asql.SchemaColumn{Name: "name", Type: "text"},
+		}, diff.Changes[0])
+	})
+}
+
+func TestSnapshotSchema(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping database test in short mode.")
+	}
+
+	db := asqltest.NewDB(t, asqltest.WithIsolation(), asqltest.WithMigrations(databasemocks.MigrationsAll))
+
+	before, err := asql.SnapshotSchema(context.Background(), db, asql.WithSnapshotSchemas("public"))
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		CREATE TYPE role AS ENUM ('user', 'admin');
+		ALTER TABLE table1 ADD COLUMN role role NOT NULL DEFAULT 'user';
+		ALTER TABLE table1 ADD CONSTRAINT table1_name_check CHECK (name <> '');
+
+		CREATE FUNCTION touch() RETURNS trigger AS $$ BEGIN RETURN NEW; END $$ LANGUAGE plpgsql;
+		CREATE TRIGGER table1_touch BEFORE UPDATE ON table1 FOR EACH ROW EXECUTE FUNCTION touch();
+	`)
+	require.NoError(t, err)
+
+	after, err := asql.SnapshotSchema(context.Background(), db, asql.WithSnapshotSchemas("public"))
+	require.NoError(t, err)
+
+	public, ok := after.Schema("public")
+	require.True(t, ok)
+	require.Equal(t, []asql.SchemaEnum{{Name: "role", Values: []string{"user", "admin"}}}, public.Enums)
+	require.Len(t, public.Functions, 1)
+	require.Equal(t, "touch", public.Functions[0].Name)
+	require.Equal(t, "trigger", public.Functions[0].Result)
+
+	table1, ok := public.Table("table1")
+	require.True(t, ok)
+	require.Equal(t, []asql.SchemaConstraint{
+		{Name: "table1_name_check", Type: "CHECK", Definition: "CHECK (name::text <> ''::text)"},
+		{Name: "table1_pkey", Type: "PRIMARY KEY", Definition: "PRIMARY KEY (id)"},
+	}, table1.Constraints)
+	require.Len(t, table1.Triggers, 1)
+	require.Equal(t, "table1_touch", table1.Triggers[0].Name)
+
+	require.Equal(t, `added column public.table1.role
+added constraint public.table1.table1_name_check
+added trigger public.table1.table1_touch
+added enum public.role
+added function public.touch()`, asql.DiffSchemas(before, after).String())
+
+	t.Run("AllSchemas", func(t *testing.T) {
+		// Excluded schemas, like the ones of test clocks, and the tables of the migrator, are not part of the snapshot.
+		_, err := db.Exec("CREATE SCHEMA asql_clock_snapshot")
+		require.NoError(t, err)
+
+		withClock, err := asql.SnapshotSchema(context.Background(), db)
+		require.NoError(t, err)
+
+		_, ok := withClock.Schema("asql_clock_snapshot")
+		require.True(t, ok)
+
+		all, err := asql.SnapshotSchema(context.Background(), db, asqltest.WithoutClockSchemas())
+		require.NoError(t, err)
+		require.Equal(t, []string{"public"}, lo.Map(all.Schemas, func(schema *asql.Schema, _ int) string {
+			return schema.Name
+		}))
+
+		for _, table := range []string{"bun_migrations", "bun_migration_locks"} {
+			_, ok := all.Schemas[0].Table(table)
+			require.False(t, ok, table)
+		}
+
+		require.Equal(t, after, all)
+	})
+
+	// Snapshots can be serialized, to be compared with another database later.
+	_, err = after.JSON()
+	require.NoError(t, err)
+}
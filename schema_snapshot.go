@@ -0,0 +1,128 @@
+package asql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/uptrace/bun"
+)
+
+// SchemaSnapshot describes the structure of a database, across its schemas. It is serializable, and does not depend
+// on internal identifiers (OIDs), so snapshots of different databases can be compared with DiffSchemas.
+type SchemaSnapshot struct {
+	// Schemas, ordered by name.
+	Schemas []*Schema `json:"schemas"`
+}
+
+// Schema returns the schema with the given name, if it exists in the snapshot.
+func (snapshot *SchemaSnapshot) Schema(name string) (*Schema, bool) {
+	for _, schema := range snapshot.Schemas {
+		if schema.Name == name {
+			return schema, true
+		}
+	}
+
+	return nil, false
+}
+
+// JSON returns the snapshot as indented JSON.
+func (snapshot *SchemaSnapshot) JSON() ([]byte, error) {
+	return json.MarshalIndent(snapshot, "", "  ")
+}
+
+// System schemas, and the schemas of temporary objects, are not part of snapshots.
+const listSchemasQuery = `
+SELECT nspname
+FROM pg_catalog.pg_namespace
+WHERE nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+  AND nspname NOT LIKE 'pg\_temp\_%'
+  AND nspname NOT LIKE 'pg\_toast\_temp\_%'
+ORDER BY nspname;
+`
+
+type snapshotSchemaConfig struct {
+	schemas         []string
+	excludedSchemas []string
+}
+
+// SnapshotSchemaOption customizes the behavior of SnapshotSchema.
+type SnapshotSchemaOption func(config *snapshotSchemaConfig)
+
+// WithSnapshotSchemas only describes the given schemas. By default, every schema is described, except system ones.
+func WithSnapshotSchemas(schemas ...string) SnapshotSchemaOption {
+	return func(config *snapshotSchemaConfig) {
+		config.schemas = append(config.schemas, schemas...)
+	}
+}
+
+// WithExcludedSchemas ignores the schemas that match any of the given patterns, using the syntax of path.Match
+// (e.g. "tmp_*").
+func WithExcludedSchemas(patterns ...string) SnapshotSchemaOption {
+	return func(config *snapshotSchemaConfig) {
+		config.excludedSchemas = append(config.excludedSchemas, patterns...)
+	}
+}
+
+func (config *snapshotSchemaConfig) isExcluded(schema string) (bool, error) {
+	for _, pattern := range config.excludedSchemas {
+		matched, err := path.Match(pattern, schema)
+		if err != nil {
+			return false, fmt.Errorf("match excluded schema %q: %w", pattern, err)
+		}
+
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SnapshotSchema describes the structure of the database: tables, columns, indexes, constraints, triggers, enums
+// and functions. See Inspect for details.
+//
+// Every schema is described, except system ones, unless WithSnapshotSchemas or WithExcludedSchemas is used. The
+// tables of the migrator and of migration audits are never described, so databases that share the same
+// migrations, but not the same history, have the same snapshot.
+func SnapshotSchema(ctx context.Context, database bun.IDB, opts ...SnapshotSchemaOption) (*SchemaSnapshot, error) {
+	config := new(snapshotSchemaConfig)
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	schemas := slices.Sorted(slices.Values(config.schemas))
+	if len(schemas) == 0 {
+		if err := database.NewRaw(listSchemasQuery).Scan(ctx, &schemas); err != nil {
+			return nil, fmt.Errorf("list schemas: %w", err)
+		}
+	}
+
+	snapshot := &SchemaSnapshot{Schemas: make([]*Schema, 0, len(schemas))}
+
+	for _, name := range schemas {
+		excluded, err := config.isExcluded(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if excluded {
+			continue
+		}
+
+		schema, err := Inspect(ctx, database, name)
+		if err != nil {
+			return nil, fmt.Errorf("inspect %s: %w", name, err)
+		}
+
+		schema.Tables = slices.DeleteFunc(schema.Tables, func(table SchemaTable) bool {
+			return slices.Contains(internalTables, table.Name)
+		})
+
+		snapshot.Schemas = append(snapshot.Schemas, schema)
+	}
+
+	return snapshot, nil
+}
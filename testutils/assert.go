@@ -0,0 +1,205 @@
+package asqltest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+// Apply an optional where clause to a query. An empty clause matches every row.
+func applyWhere(query *bun.SelectQuery, where string, args []any) *bun.SelectQuery {
+	if where == "" {
+		return query
+	}
+
+	return query.Where(where, args...)
+}
+
+func countRows(t testing.TB, db bun.IDB, model any, where string, args []any) (int, bool) {
+	t.Helper()
+
+	count, err := applyWhere(db.NewSelect().Model(model), where, args).Count(context.Background())
+	if err != nil {
+		t.Errorf("count rows: %v", err)
+		return 0, false
+	}
+
+	return count, true
+}
+
+// Describe the rows targeted by an assertion, for error messages.
+func describeRows(db bun.IDB, model any, where string, args []any) string {
+	table := db.Dialect().Tables().Get(reflect.TypeOf(model)).Name
+	if where == "" {
+		return "table " + table
+	}
+
+	return "table " + table + " where " + db.NewRaw(where, args...).String()
+}
+
+// AssertRowCount checks the number of rows of the model table that match the where clause. The clause uses the
+// syntax of bun.SelectQuery.Where, and can be left empty to count every row.
+//
+//	asqltest.AssertRowCount(t, tx, (*User)(nil), 1, "email = ?", "john@example.com")
+func AssertRowCount(t testing.TB, db bun.IDB, model any, expected int, where string, args ...any) bool {
+	t.Helper()
+
+	count, ok := countRows(t, db, model, where, args)
+	if !ok {
+		return false
+	}
+
+	return assert.Equal(t, expected, count, "number of rows in %s", describeRows(db, model, where, args))
+}
+
+// AssertRowExists checks that at least one row of the model table matches the where clause.
+func AssertRowExists(t testing.TB, db bun.IDB, model any, where string, args ...any) bool {
+	t.Helper()
+
+	count, ok := countRows(t, db, model, where, args)
+	if !ok {
+		return false
+	}
+
+	if count == 0 {
+		t.Errorf("expected a row in %s, found none", describeRows(db, model, where, args))
+		return false
+	}
+
+	return true
+}
+
+// AssertNoRow checks that no row of the model table matches the where clause.
+func AssertNoRow(t testing.TB, db bun.IDB, model any, where string, args ...any) bool {
+	t.Helper()
+
+	count, ok := countRows(t, db, model, where, args)
+	if !ok {
+		return false
+	}
+
+	if count > 0 {
+		t.Errorf("expected no row in %s, found %d", describeRows(db, model, where, args), count)
+		return false
+	}
+
+	return true
+}
+
+type tableAssertConfig struct {
+	ignoredColumns []string
+	order          []string
+	where          string
+	whereArgs      []any
+}
+
+// TableAssertOption customizes AssertTableContents.
+type TableAssertOption func(config *tableAssertConfig)
+
+// IgnoreColumns excludes columns from the comparison, for example timestamps set by the database.
+func IgnoreColumns(columns ...string) TableAssertOption {
+	return func(config *tableAssertConfig) {
+		config.ignoredColumns = append(config.ignoredColumns, columns...)
+	}
+}
+
+// OrderBy sets the order rows are read in. Defaults to the primary key.
+func OrderBy(orders ...string) TableAssertOption {
+	return func(config *tableAssertConfig) {
+		config.order = append(config.order, orders...)
+	}
+}
+
+// WhereRows restricts the comparison to the rows matching the where clause.
+func WhereRows(where string, args ...any) TableAssertOption {
+	return func(config *tableAssertConfig) {
+		config.where = where
+		config.whereArgs = args
+	}
+}
+
+// Reset the ignored columns of the models to their zero value, so they do not take part in the comparison.
+func maskColumns[T any](table *schema.Table, models []*T, columns []string) error {
+	for _, column := range columns {
+		field, err := table.Field(column)
+		if err != nil {
+			return err
+		}
+
+		for _, model := range models {
+			if model == nil {
+				continue
+			}
+
+			value := field.Value(reflect.ValueOf(model).Elem())
+			value.Set(reflect.Zero(value.Type()))
+		}
+	}
+
+	return nil
+}
+
+// AssertTableContents checks that the table of model T contains exactly the expected rows, in order. On failure,
+// it prints a diff between the expected and actual rows.
+//
+// Rows are read in the order of the primary key, unless OrderBy is used. The expected models are not modified.
+func AssertTableContents[T any](t testing.TB, db bun.IDB, expected []*T, opts ...TableAssertOption) bool {
+	t.Helper()
+
+	config := new(tableAssertConfig)
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	table := db.Dialect().Tables().Get(reflect.TypeOf((*T)(nil)).Elem())
+
+	actual := make([]*T, 0)
+
+	query := applyWhere(db.NewSelect().Model(&actual), config.where, config.whereArgs)
+	for _, order := range config.order {
+		query = query.OrderExpr(order)
+	}
+
+	// Primary key columns are quoted, so reserved or mixed-case names work.
+	if len(config.order) == 0 {
+		for _, pk := range table.PKs {
+			query = query.OrderExpr("?", pk.SQLName)
+		}
+	}
+
+	if err := query.Scan(context.Background()); err != nil {
+		t.Errorf("read %s: %v", table.Name, err)
+		return false
+	}
+
+	// Work on copies, so the masks do not alter the models of the caller.
+	expectedCopy := make([]*T, len(expected))
+	for i, model := range expected {
+		if model != nil {
+			modelCopy := *model
+			expectedCopy[i] = &modelCopy
+		}
+	}
+
+	if err := maskColumns(table, expectedCopy, config.ignoredColumns); err != nil {
+		t.Errorf("ignore columns: %v", err)
+		return false
+	}
+
+	if err := maskColumns(table, actual, config.ignoredColumns); err != nil {
+		t.Errorf("ignore columns: %v", err)
+		return false
+	}
+
+	message := "contents of table " + table.Name
+	if len(config.ignoredColumns) > 0 {
+		message += " (ignoring " + strings.Join(config.ignoredColumns, ", ") + ")"
+	}
+
+	return assert.Equal(t, expectedCopy, actual, message)
+}
@@ -0,0 +1,77 @@
+package asqltest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+// A table whose primary key is a reserved, mixed-case word.
+type quotedKeyModel struct {
+	bun.BaseModel `bun:"table:quoted_keys"`
+
+	Order int    `bun:"Order,pk"`
+	Name  string `bun:"name"`
+}
+
+func TestAssertions(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+	tx := asqltest.NewTx(t, db,
+		&databasemocks.Table1Model{ID: 2, Name: "bar"},
+		&databasemocks.Table1Model{ID: 1, Name: "foo"},
+	)
+
+	t.Run("Success", func(t *testing.T) {
+		require.True(t, asqltest.AssertRowCount(t, tx, (*databasemocks.Table1Model)(nil), 2, ""))
+		require.True(t, asqltest.AssertRowCount(t, tx, (*databasemocks.Table1Model)(nil), 1, "name = ?", "foo"))
+		require.True(t, asqltest.AssertRowExists(t, tx, (*databasemocks.Table1Model)(nil), "id = ?", 2))
+		require.True(t, asqltest.AssertNoRow(t, tx, (*databasemocks.Table1Model)(nil), "id = ?", 3))
+		require.True(t, asqltest.AssertNoRow(t, tx, (*databasemocks.Table2Model)(nil), ""))
+
+		require.True(t, asqltest.AssertTableContents(t, tx, []*databasemocks.Table1Model{
+			{ID: 1, Name: "foo"},
+			{ID: 2, Name: "bar"},
+		}))
+
+		expected := []*databasemocks.Table1Model{{ID: 2, Name: "other"}, {ID: 1, Name: "another"}}
+		require.True(t, asqltest.AssertTableContents(t, tx, expected,
+			asqltest.IgnoreColumns("name"),
+			asqltest.OrderBy("id DESC"),
+		))
+		// Expected models are left untouched.
+		require.Equal(t, "other", expected[0].Name)
+
+		require.True(t, asqltest.AssertTableContents(t, tx, []*databasemocks.Table1Model{{ID: 1, Name: "foo"}},
+			asqltest.WhereRows("name = ?", "foo"),
+		))
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		mockT := new(testing.T)
+
+		require.False(t, asqltest.AssertRowCount(mockT, tx, (*databasemocks.Table1Model)(nil), 3, ""))
+		require.False(t, asqltest.AssertRowExists(mockT, tx, (*databasemocks.Table1Model)(nil), "id = ?", 3))
+		require.False(t, asqltest.AssertNoRow(mockT, tx, (*databasemocks.Table1Model)(nil), "id = ?", 1))
+		require.False(t, asqltest.AssertTableContents(mockT, tx, []*databasemocks.Table1Model{{ID: 1, Name: "foo"}}))
+	})
+
+	t.Run("QuotedPrimaryKey", func(t *testing.T) {
+		ctx := context.Background()
+
+		_, err := tx.Exec(`CREATE TABLE quoted_keys ("Order" INT PRIMARY KEY, name TEXT NOT NULL)`)
+		require.NoError(t, err)
+
+		_, err = tx.NewInsert().Model(&[]*quotedKeyModel{{Order: 2, Name: "bar"}, {Order: 1, Name: "foo"}}).Exec(ctx)
+		require.NoError(t, err)
+
+		require.True(t, asqltest.AssertTableContents(t, tx, []*quotedKeyModel{
+			{Order: 1, Name: "foo"},
+			{Order: 2, Name: "bar"},
+		}))
+	})
+}
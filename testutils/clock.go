@@ -0,0 +1,447 @@
+package asqltest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/a-novel-kit/asql"
+)
+
+// ErrPooledClock is returned when a Clock is created on a connection pool. The overrides only apply to the
+// connection that received them, so queries sent through the pool would randomly see the real time.
+var ErrPooledClock = errors.New("clock cannot be bound to a connection pool, use NewConnClock or a transaction")
+
+// Prefix of the schemas that hold the overrides of a Clock. Each clock has its own schema, so clocks from
+// different connections do not interfere with each other.
+const clockSchemaPrefix = "asql_clock_"
+
+// Schema that held the overrides of FreezeTime in earlier versions. Databases may still have it.
+const legacyClockSchema = "override"
+
+// WithoutClockSchemas excludes the schemas of clocks from schema snapshots, so databases compare the same whether
+// their time was frozen or not.
+func WithoutClockSchemas() asql.SnapshotSchemaOption {
+	return asql.WithExcludedSchemas(clockSchemaPrefix+"*", legacyClockSchema)
+}
+
+// Every function overridden by a Clock. The keywords CURRENT_TIMESTAMP and LOCALTIMESTAMP are resolved by the
+// parser to pg_catalog directly, so they ignore the search path: column defaults that use them are rewritten
+// instead (see listClockDefaultsQuery).
+//
+// The schema of the clock is bound to ?0, and the expression computing the fake time to ?1. The last statement
+// sets the search path, for the transaction only if ?2 is true.
+const setClockFn = `
+CREATE SCHEMA IF NOT EXISTS ?0;
+
+CREATE OR REPLACE FUNCTION ?0.now()
+  RETURNS timestamptz VOLATILE PARALLEL SAFE AS $$ SELECT ?1 $$ LANGUAGE sql;
+CREATE OR REPLACE FUNCTION ?0.transaction_timestamp()
+  RETURNS timestamptz VOLATILE PARALLEL SAFE AS $$ SELECT ?1 $$ LANGUAGE sql;
+CREATE OR REPLACE FUNCTION ?0.statement_timestamp()
+  RETURNS timestamptz VOLATILE PARALLEL SAFE AS $$ SELECT ?1 $$ LANGUAGE sql;
+CREATE OR REPLACE FUNCTION ?0.clock_timestamp()
+  RETURNS timestamptz VOLATILE PARALLEL SAFE AS $$ SELECT ?1 $$ LANGUAGE sql;
+
+SELECT pg_catalog.set_config('search_path', ?3 || ',pg_temp,"$user",public,pg_catalog', ?2);
+`
+
+const unsetClockFn = `
+DROP SCHEMA IF EXISTS ?0 CASCADE;
+SELECT pg_catalog.set_config('search_path', ?1, ?2);
+`
+
+// Drop the schemas of clocks that were not restored, for example because a test crashed.
+const clearClocksFn = `
+DO $$
+DECLARE
+    schema_name text;
+BEGIN
+    FOR schema_name IN SELECT nspname FROM pg_catalog.pg_namespace WHERE nspname LIKE 'asql\_clock\_%' LOOP
+        EXECUTE format('DROP SCHEMA %I CASCADE', schema_name);
+    END LOOP;
+END
+$$;
+`
+
+// List the column defaults that read the current time, in the tables owned by the current role. The keywords are
+// rendered by pg_get_expr in upper case, without their schema.
+const listClockDefaultsQuery = `
+SELECT nsp.nspname AS schema_name,
+       cls.relname AS table_name,
+       att.attname AS column_name,
+       pg_catalog.pg_get_expr(def.adbin, def.adrelid) AS expression
+FROM pg_catalog.pg_attrdef def
+JOIN pg_catalog.pg_attribute att ON att.attrelid = def.adrelid AND att.attnum = def.adnum
+JOIN pg_catalog.pg_class cls ON cls.oid = def.adrelid
+JOIN pg_catalog.pg_namespace nsp ON nsp.oid = cls.relnamespace
+WHERE cls.relkind IN ('r', 'p')
+  AND NOT att.attisdropped
+  AND nsp.nspname NOT IN ('pg_catalog', 'information_schema')
+  AND nsp.nspname NOT LIKE 'pg\_%'
+  AND nsp.nspname NOT LIKE 'asql\_clock\_%'
+  AND pg_catalog.pg_has_role(cls.relowner, 'USAGE')
+  AND pg_catalog.pg_get_expr(def.adbin, def.adrelid) ~* '(now|_timestamp)\(\)|current_timestamp|localtimestamp|current_date'
+ORDER BY 1, 2, 3;
+`
+
+const setColumnDefaultQuery = `ALTER TABLE ?.? ALTER COLUMN ? SET DEFAULT ?`
+
+// Match the calls to the time functions, and the time keywords with their optional precision.
+var clockDefaultRegexp = regexp.MustCompile(
+	`(?i)\b(?:pg_catalog\.)?(?:(now|transaction_timestamp|statement_timestamp|clock_timestamp)\(\)|` +
+		`(current_timestamp|localtimestamp|current_date)(?:\(\d+\))?)`,
+)
+
+// A column default that reads the current time.
+type clockDefault struct {
+	SchemaName string `bun:"schema_name"`
+	TableName  string `bun:"table_name"`
+	ColumnName string `bun:"column_name"`
+	Expression string `bun:"expression"`
+}
+
+type clockConfig struct {
+	ticking        bool
+	columnDefaults bool
+}
+
+// ClockOption customizes a Clock.
+type ClockOption func(config *clockConfig)
+
+// WithTicking makes the clock tick from the time it is set to, instead of staying frozen.
+func WithTicking() ClockOption {
+	return func(config *clockConfig) {
+		config.ticking = true
+	}
+}
+
+// WithColumnDefaults makes the column defaults that read the current time use the clock. Rewriting a default takes
+// an exclusive lock on its table, until the end of the transaction.
+//
+// On a clock bound to a transaction, the changes are undone when the transaction ends. On a clock bound to a
+// dedicated connection, defaults are rewritten for every connection to the database, until the clock is restored:
+// only use it on a database that is not shared with other tests (see WithIsolation).
+func WithColumnDefaults() ClockOption {
+	return func(config *clockConfig) {
+		config.columnDefaults = true
+	}
+}
+
+// Clock controls the time returned by the database. The time can be moved after the clock is created.
+//
+// The following functions are overridden, and all return the same time: now(), transaction_timestamp(),
+// statement_timestamp() and clock_timestamp(). The SQL keywords CURRENT_TIMESTAMP, LOCALTIMESTAMP and CURRENT_DATE
+// are resolved by Postgres without looking at the search path, so they keep returning the real time when used in
+// queries.
+//
+// Column defaults that read the current time, including with those keywords (e.g. DEFAULT CURRENT_TIMESTAMP), are
+// only rewritten to use the clock when it is created with WithColumnDefaults.
+//
+// A clock is bound to a single connection (see NewConnClock) or transaction, and does not affect the others.
+//
+// Clock implements asql.Clock. Inject it in the tested code, so the Go time stays in sync with the database:
+//
+//	clock, conn := asqltest.NewTestConnClock(t, db, date)
+//	service := NewService(conn, clock)
+//
+//	require.NoError(t, clock.Advance(24 * time.Hour)) // Both service.clock.Now() and now() moved by a day.
+type Clock struct {
+	db      bun.IDB
+	ticking bool
+	// Overrides set in a transaction are scoped to it, and undone when it ends.
+	local bool
+
+	schema string
+	// Search path of the connection before the clock was created, restored by Restore.
+	searchPath string
+	// Column defaults rewritten to use the clock, with their original expression.
+	defaults []clockDefault
+	// Connection reserved by NewConnClock, if any.
+	conn *ClockConn
+
+	// Time the clock was last set to.
+	start time.Time
+	// Real time when the clock was last set, so a ticking clock can compute the time elapsed since.
+	goAnchor time.Time
+
+	// The tested code may read the time concurrently with the test moving it.
+	mu sync.RWMutex
+}
+
+var _ asql.Clock = (*Clock)(nil)
+
+// NewClock overrides the time functions of the given connection or transaction, so they return the given date.
+//
+// In a transaction, the overrides are scoped to it (like SET LOCAL), and are undone when it is committed or rolled
+// back. A *bun.DB is a pool of connections, so it is rejected with ErrPooledClock.
+func NewClock(db bun.IDB, date time.Time, opts ...ClockOption) (*Clock, error) {
+	if _, ok := db.(*bun.DB); ok {
+		return nil, ErrPooledClock
+	}
+
+	config := new(clockConfig)
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	_, local := db.(bun.Tx)
+	clock := &Clock{db: db, ticking: config.ticking, local: local}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("generate schema name: %w", err)
+	}
+
+	clock.schema = clockSchemaPrefix + hex.EncodeToString(suffix)
+
+	if err := db.NewRaw("SELECT pg_catalog.current_setting('search_path')").
+		Scan(context.Background(), &clock.searchPath); err != nil {
+		return nil, fmt.Errorf("read search path: %w", err)
+	}
+
+	// Defaults are listed before the search path changes, so their expression is rendered as it was written.
+	var defaults []clockDefault
+	if config.columnDefaults {
+		if err := db.NewRaw(listClockDefaultsQuery).Scan(context.Background(), &defaults); err != nil {
+			return nil, fmt.Errorf("list column defaults: %w", err)
+		}
+	}
+
+	if err := clock.Set(date); err != nil {
+		return nil, err
+	}
+
+	if err := clock.overrideDefaults(defaults); err != nil {
+		return nil, errors.Join(err, clock.Restore())
+	}
+
+	return clock, nil
+}
+
+// Rewrite the given column defaults, so they read the time from the clock. Functions are referenced by OID, so the
+// defaults follow the clock when it is set again.
+func (clock *Clock) overrideDefaults(defaults []clockDefault) error {
+	// The schema name is generated, and does not need escaping.
+	now := `"` + clock.schema + `".now()`
+
+	for _, columnDefault := range defaults {
+		expression := clockDefaultRegexp.ReplaceAllStringFunc(columnDefault.Expression, func(match string) string {
+			switch keyword := strings.ToLower(clockDefaultRegexp.FindStringSubmatch(match)[2]); keyword {
+			case "localtimestamp":
+				return "(" + now + ")::timestamp"
+			case "current_date":
+				return "(" + now + ")::date"
+			default:
+				return now
+			}
+		})
+
+		if err := clock.setDefault(columnDefault, expression); err != nil {
+			return err
+		}
+
+		clock.defaults = append(clock.defaults, columnDefault)
+	}
+
+	return nil
+}
+
+func (clock *Clock) setDefault(columnDefault clockDefault, expression string) error {
+	_, err := clock.db.ExecContext(
+		context.Background(), setColumnDefaultQuery,
+		bun.Ident(columnDefault.SchemaName), bun.Ident(columnDefault.TableName), bun.Ident(columnDefault.ColumnName),
+		bun.Safe(expression),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"set default of %s.%s.%s: %w",
+			columnDefault.SchemaName, columnDefault.TableName, columnDefault.ColumnName, err,
+		)
+	}
+
+	return nil
+}
+
+// ClockConn is a connection reserved by NewConnClock. Closing it restores the clock first, so the connection goes
+// back to the pool without the overrides.
+type ClockConn struct {
+	bun.Conn
+
+	clock *Clock
+}
+
+// Close restores the clock, and releases the connection. If the clock cannot be restored, the connection is
+// discarded instead of going back to the pool.
+func (conn ClockConn) Close() error {
+	if err := conn.clock.Restore(); err != nil {
+		return errors.Join(err, discardConn(conn.Conn))
+	}
+
+	return conn.Conn.Close()
+}
+
+// Close the connection, and remove it from the pool.
+func discardConn(conn bun.Conn) error {
+	err := conn.Raw(func(any) error { return driver.ErrBadConn })
+	if errors.Is(err, driver.ErrBadConn) {
+		return nil
+	}
+
+	return err
+}
+
+// NewConnClock reserves a connection from the pool, and creates a Clock on it. Queries must be sent through the
+// returned connection to see the overrides. The connection must be closed by the caller, which also restores the
+// clock.
+func NewConnClock(ctx context.Context, db *bun.DB, date time.Time, opts ...ClockOption) (*Clock, ClockConn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, ClockConn{}, fmt.Errorf("acquire connection: %w", err)
+	}
+
+	clock, err := NewClock(conn, date, opts...)
+	if err != nil {
+		// The search path may have changed before the failure.
+		return nil, ClockConn{}, errors.Join(err, discardConn(conn))
+	}
+
+	clock.conn = &ClockConn{Conn: conn, clock: clock}
+
+	return clock, *clock.conn, nil
+}
+
+// Set moves the clock to the given date. A ticking clock resumes ticking from there.
+func (clock *Clock) Set(date time.Time) error {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.set(date)
+}
+
+func (clock *Clock) set(date time.Time) error {
+	ctx := context.Background()
+
+	expr := bun.SafeQuery("?::timestamptz", date)
+	goAnchor := time.Now()
+
+	if clock.ticking {
+		// The real clock_timestamp is called explicitly, since the unqualified one is overridden.
+		var dbAnchor time.Time
+		if err := clock.db.NewRaw("SELECT pg_catalog.clock_timestamp()").Scan(ctx, &dbAnchor); err != nil {
+			return fmt.Errorf("read database time: %w", err)
+		}
+
+		goAnchor = time.Now()
+		expr = bun.SafeQuery("?::timestamptz + (pg_catalog.clock_timestamp() - ?::timestamptz)", date, dbAnchor)
+	}
+
+	_, err := clock.db.ExecContext(ctx, setClockFn, bun.Ident(clock.schema), expr, clock.local, clock.schema)
+	if err != nil {
+		return fmt.Errorf("exec query: %w", err)
+	}
+
+	clock.start = date
+	clock.goAnchor = goAnchor
+
+	return nil
+}
+
+// Now returns the current time of the clock. For a ticking clock, the time elapsed since the clock was set is
+// measured on the Go side, and may slightly differ from the database.
+func (clock *Clock) Now() time.Time {
+	clock.mu.RLock()
+	defer clock.mu.RUnlock()
+
+	return clock.now()
+}
+
+func (clock *Clock) now() time.Time {
+	if !clock.ticking {
+		return clock.start
+	}
+
+	return clock.start.Add(time.Since(clock.goAnchor))
+}
+
+// Advance moves the clock forward by the given duration (or backward, if negative).
+func (clock *Clock) Advance(duration time.Duration) error {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.set(clock.now().Add(duration))
+}
+
+// Restore removes the overrides, and puts the original column defaults back, so the connection returns the real
+// time again. Restoring a clock twice has no effect.
+func (clock *Clock) Restore() error {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	// Defaults depend on the functions of the clock, so they must be restored before the functions are dropped.
+	for len(clock.defaults) > 0 {
+		columnDefault := clock.defaults[len(clock.defaults)-1]
+		if err := clock.setDefault(columnDefault, columnDefault.Expression); err != nil {
+			return err
+		}
+
+		clock.defaults = clock.defaults[:len(clock.defaults)-1]
+	}
+
+	_, err := clock.db.ExecContext(
+		context.Background(), unsetClockFn, bun.Ident(clock.schema), clock.searchPath, clock.local,
+	)
+	if err != nil {
+		return fmt.Errorf("exec query: %w", err)
+	}
+
+	return nil
+}
+
+// NewTestClock creates a Clock on the given connection or transaction, and restores the real time when the test
+// completes. The test fails immediately if the clock cannot be created.
+func NewTestClock(t testing.TB, db bun.IDB, date time.Time, opts ...ClockOption) *Clock {
+	t.Helper()
+
+	clock, err := NewClock(db, date, opts...)
+	if err != nil {
+		t.Fatalf("create clock: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := clock.Restore(); err != nil {
+			t.Errorf("restore time: %v", err)
+		}
+	})
+
+	return clock
+}
+
+// NewTestConnClock creates a Clock on a dedicated connection, like NewConnClock. The clock is restored, and the
+// connection closed, when the test completes.
+func NewTestConnClock(t testing.TB, db *bun.DB, date time.Time, opts ...ClockOption) (*Clock, ClockConn) {
+	t.Helper()
+
+	clock, conn, err := NewConnClock(context.Background(), db, date, opts...)
+	if err != nil {
+		t.Fatalf("create clock: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := conn.Close(); err != nil {
+			t.Errorf("restore time: %v", err)
+		}
+	})
+
+	return clock, conn
+}
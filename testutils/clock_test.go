@@ -0,0 +1,183 @@
+package asqltest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+
+	"github.com/a-novel-kit/asql"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func readClockTimes(t *testing.T, db bun.IDB) []time.Time {
+	t.Helper()
+
+	var now, transactionTimestamp, statementTimestamp, clockTimestamp time.Time
+	require.NoError(t, db.NewRaw(
+		"SELECT now(), transaction_timestamp(), statement_timestamp(), clock_timestamp()",
+	).Scan(context.Background(), &now, &transactionTimestamp, &statementTimestamp, &clockTimestamp))
+
+	return []time.Time{now, transactionTimestamp, statementTimestamp, clockTimestamp}
+}
+
+func TestClock(t *testing.T) {
+	db := asqltest.NewDB(t)
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Frozen", func(t *testing.T) {
+		clock, conn := asqltest.NewTestConnClock(t, db, start)
+
+		for _, dbTime := range readClockTimes(t, conn) {
+			require.Equal(t, start, dbTime)
+		}
+
+		require.NoError(t, clock.Advance(24*time.Hour))
+
+		for _, dbTime := range readClockTimes(t, conn) {
+			require.Equal(t, start.Add(24*time.Hour), dbTime)
+		}
+		require.Equal(t, start.Add(24*time.Hour), clock.Now())
+
+		require.NoError(t, clock.Set(start))
+
+		for _, dbTime := range readClockTimes(t, conn) {
+			require.Equal(t, start, dbTime)
+		}
+
+		// Other connections are not affected.
+		for _, dbTime := range readClockTimes(t, db) {
+			require.WithinDuration(t, time.Now(), dbTime, time.Minute)
+		}
+	})
+
+	t.Run("Ticking", func(t *testing.T) {
+		clock, conn := asqltest.NewTestConnClock(t, db, start, asqltest.WithTicking())
+
+		time.Sleep(50 * time.Millisecond)
+
+		for _, dbTime := range readClockTimes(t, conn) {
+			require.True(t, dbTime.After(start.Add(50*time.Millisecond)))
+			require.WithinDuration(t, start, dbTime, time.Second)
+		}
+
+		require.NoError(t, clock.Advance(time.Hour))
+
+		for _, dbTime := range readClockTimes(t, conn) {
+			require.WithinDuration(t, start.Add(time.Hour), dbTime, time.Second)
+		}
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		tx := asqltest.NewTx(t, db)
+
+		clock, err := asqltest.NewClock(tx, start)
+		require.NoError(t, err)
+
+		for _, dbTime := range readClockTimes(t, tx) {
+			require.Equal(t, start, dbTime)
+		}
+
+		require.NoError(t, clock.Advance(time.Hour))
+
+		for _, dbTime := range readClockTimes(t, tx) {
+			require.Equal(t, start.Add(time.Hour), dbTime)
+		}
+
+		// Ending the transaction undoes the overrides.
+		require.NoError(t, tx.Rollback())
+
+		for _, dbTime := range readClockTimes(t, db) {
+			require.WithinDuration(t, time.Now(), dbTime, time.Minute)
+		}
+	})
+
+	t.Run("ColumnDefaults", func(t *testing.T) {
+		tx := asqltest.NewTx(t, db)
+
+		_, err := tx.Exec(`
+			CREATE TABLE clock_defaults (
+				id INT PRIMARY KEY,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT (now() + interval '1 hour'),
+				local_at TIMESTAMP NOT NULL DEFAULT LOCALTIMESTAMP(3),
+				day DATE NOT NULL DEFAULT CURRENT_DATE
+			);
+		`)
+		require.NoError(t, err)
+
+		readDefaults := func(id int) []bool {
+			_, err := tx.Exec("INSERT INTO clock_defaults (id) VALUES (?)", id)
+			require.NoError(t, err)
+
+			var createdAt, updatedAt, localAt, day bool
+			require.NoError(t, tx.NewRaw(`
+				SELECT created_at = ?0::timestamptz,
+				       updated_at = ?0::timestamptz + interval '1 hour',
+				       local_at = ?0::timestamptz::timestamp,
+				       day = ?0::timestamptz::date
+				FROM clock_defaults WHERE id = ?1
+			`, start, id).Scan(context.Background(), &createdAt, &updatedAt, &localAt, &day))
+
+			return []bool{createdAt, updatedAt, localAt, day}
+		}
+
+		// Defaults are left untouched by default.
+		plain, err := asqltest.NewClock(tx, start)
+		require.NoError(t, err)
+		require.Equal(t, []bool{false, false, false, false}, readDefaults(0))
+		require.NoError(t, plain.Restore())
+
+		clock, err := asqltest.NewClock(tx, start, asqltest.WithColumnDefaults())
+		require.NoError(t, err)
+
+		require.Equal(t, []bool{true, true, true, true}, readDefaults(1))
+
+		// The original defaults are restored with the clock.
+		require.NoError(t, clock.Restore())
+		require.Equal(t, []bool{false, false, false, false}, readDefaults(2))
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		_, err := asqltest.NewClock(db, start)
+		require.ErrorIs(t, err, asqltest.ErrPooledClock)
+	})
+}
+
+func TestConnClockClose(t *testing.T) {
+	db := asqltest.NewDB(t)
+	// The reserved connection is the only one of the pool, so it is reused by the next queries.
+	db.SetMaxOpenConns(1)
+
+	_, conn, err := asqltest.NewConnClock(context.Background(), db, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	// Closing the connection, without restoring the clock first, removes the overrides.
+	require.NoError(t, conn.Close())
+
+	for _, dbTime := range readClockTimes(t, db) {
+		require.WithinDuration(t, time.Now(), dbTime, time.Minute)
+	}
+
+	var searchPath string
+	require.NoError(t, db.NewRaw("SELECT current_setting('search_path')").Scan(context.Background(), &searchPath))
+	require.NotContains(t, searchPath, "asql_clock_")
+}
+
+func TestClockSyncsGoTime(t *testing.T) {
+	db := asqltest.NewDB(t)
+
+	clock, conn := asqltest.NewTestConnClock(t, db, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// The clock can be injected in code that depends on asql.Clock.
+	var goClock asql.Clock = clock
+
+	require.NoError(t, clock.Advance(48*time.Hour))
+
+	for _, dbTime := range readClockTimes(t, conn) {
+		require.Equal(t, goClock.Now(), dbTime)
+	}
+}
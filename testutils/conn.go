@@ -4,6 +4,8 @@ import (
 	"context"
 	"embed"
 	"fmt"
+	"io/fs"
+	"testing"
 
 	"github.com/uptrace/bun"
 
@@ -14,20 +16,33 @@ import (
 
 // OpenTestDB opens a connection to a test DB.
 //
-// The test DB must be available under the value stored in DSN.
+// The test DB must be available under the DSN returned by GetTestDSN.
 func OpenTestDB(sqlMigrations *embed.FS) (*bun.DB, func(), error) {
-	database, closer, err := asql.OpenDB(TestDSN)
+	if sqlMigrations == nil {
+		return openTestDB(nil)
+	}
+
+	return openTestDB(sqlMigrations)
+}
+
+func openTestDB(sqlMigrations fs.FS) (*bun.DB, func(), error) {
+	database, closer, err := asql.OpenDB(GetTestDSN())
 	if err != nil {
 		return nil, nil, fmt.Errorf("open db: %w", err)
 	}
 
 	// Just in case something went wrong on latest run.
-	ClearTestDB(database)
+	if err = clearTestDB(database); err != nil {
+		closer()
+		return nil, nil, err
+	}
+
 	if sqlMigrations == nil {
 		return database, closer, nil
 	}
 
-	if err = asql.Migrate(database, *sqlMigrations, loggers.NewTerminal()); err != nil {
+	_, err = asql.MigrateWithSink(database, sqlMigrations, asql.NewQuicklogMigrationSink(loggers.NewTerminal()))
+	if err != nil {
 		closer()
 		return nil, nil, fmt.Errorf("migrate: %w", err)
 	}
@@ -35,16 +50,77 @@ func OpenTestDB(sqlMigrations *embed.FS) (*bun.DB, func(), error) {
 	return database, closer, nil
 }
 
+type dbConfig struct {
+	sqlMigrations fs.FS
+	isolated      bool
+}
+
+// DBOption customizes the database returned by NewDB.
+type DBOption func(config *dbConfig)
+
+// WithMigrations applies the given migrations to the test database.
+func WithMigrations(sqlMigrations fs.FS) DBOption {
+	return func(config *dbConfig) {
+		config.sqlMigrations = sqlMigrations
+	}
+}
+
+// WithIsolation gives the test its own database, cloned from a migrated template. See OpenIsolatedTestDB.
+func WithIsolation() DBOption {
+	return func(config *dbConfig) {
+		config.isolated = true
+	}
+}
+
+// NewDB opens a connection to a test DB, and closes it when the test completes. The test fails immediately if the
+// database cannot be opened.
+//
+// By default, it behaves like OpenTestDB: the shared test DB is cleared before use.
+func NewDB(t testing.TB, opts ...DBOption) *bun.DB {
+	t.Helper()
+
+	config := new(dbConfig)
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.isolated {
+		return OpenIsolatedTestDB(t, config.sqlMigrations)
+	}
+
+	database, closer, err := openTestDB(config.sqlMigrations)
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+
+	t.Cleanup(closer)
+
+	return database
+}
+
+// ClearTestDB resets the public schema of the test DB, and removes the leftovers of clocks. It panics on failure.
 func ClearTestDB(database *bun.DB) {
+	if err := clearTestDB(database); err != nil {
+		panic(err)
+	}
+}
+
+func clearTestDB(database *bun.DB) error {
 	ctx := context.Background()
 	if _, err := database.ExecContext(ctx, "DROP SCHEMA public CASCADE; CREATE SCHEMA public;"); err != nil {
-		panic(err)
+		return fmt.Errorf("reset public schema: %w", err)
 	}
 
 	if _, err := database.ExecContext(ctx, "GRANT ALL ON SCHEMA public TO public;"); err != nil {
-		panic(err)
+		return fmt.Errorf("grant public schema: %w", err)
 	}
-	if _, err := database.ExecContext(ctx, "GRANT ALL ON SCHEMA public TO test;"); err != nil {
-		panic(err)
+	if _, err := database.ExecContext(ctx, "GRANT ALL ON SCHEMA public TO CURRENT_USER;"); err != nil {
+		return fmt.Errorf("grant public schema: %w", err)
 	}
+
+	if _, err := database.ExecContext(ctx, clearClocksFn); err != nil {
+		return fmt.Errorf("clear clocks: %w", err)
+	}
+
+	return nil
 }
@@ -44,3 +44,26 @@ func TestConn(t *testing.T) {
 		require.Error(t, db.NewSelect().Model(&databasemocks.Table3Model{}).Where("id = ?", 42).Scan(context.Background()))
 	})
 }
+
+func TestNewDB(t *testing.T) {
+	t.Run("NoMigrations", func(t *testing.T) {
+		db := asqltest.NewDB(t)
+
+		_, err := db.Exec("SELECT 1")
+		require.NoError(t, err)
+	})
+
+	t.Run("WithMigrations", func(t *testing.T) {
+		db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+
+		_, err := db.NewInsert().Model(&databasemocks.Table3Model{ID: 42, Name: "foo"}).Exec(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("Isolated", func(t *testing.T) {
+		db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll), asqltest.WithIsolation())
+
+		_, err := db.NewInsert().Model(&databasemocks.Table3Model{ID: 42, Name: "foo"}).Exec(context.Background())
+		require.NoError(t, err)
+	})
+}
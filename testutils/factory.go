@@ -0,0 +1,159 @@
+package asqltest
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/uptrace/bun"
+)
+
+// Association builds or creates a record a model depends on, and links it to the model. See Associate.
+type Association[T any] interface {
+	build(model *T)
+	create(ctx context.Context, database bun.IDB, model *T) error
+}
+
+type association[T, A any] struct {
+	factory *Factory[A]
+	link    func(model *T, associated *A)
+}
+
+func (assoc *association[T, A]) build(model *T) {
+	assoc.link(model, assoc.factory.Build())
+}
+
+func (assoc *association[T, A]) create(ctx context.Context, database bun.IDB, model *T) error {
+	associated, err := assoc.factory.Create(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	assoc.link(model, associated)
+
+	return nil
+}
+
+// Associate returns an association, that uses the given factory to build the associated record, then calls link
+// to reference it from the model (usually by setting a foreign key).
+func Associate[T, A any](factory *Factory[A], link func(model *T, associated *A)) Association[T] {
+	return &association[T, A]{factory: factory, link: link}
+}
+
+// Factory builds models of type T with default values, so tests only have to set the fields they care about.
+//
+//	users := asqltest.NewFactory(func(seq int) *User {
+//		return &User{ID: int64(seq), Email: fmt.Sprintf("user-%d@example.com", seq)}
+//	})
+//
+//	user, err := users.Create(ctx, tx, func(user *User) { user.Name = "john" })
+type Factory[T any] struct {
+	defaults     func(seq int) *T
+	associations []Association[T]
+
+	// Shared by the copies returned by WithAssociations, so they never produce the same sequence number.
+	sequence *atomic.Int64
+}
+
+// NewFactory creates a factory that uses defaults to build new models. Defaults receive a sequence number,
+// starting at 1 and incremented for every model built, that can be used to generate unique values.
+func NewFactory[T any](defaults func(seq int) *T) *Factory[T] {
+	return &Factory[T]{
+		defaults: defaults,
+		sequence: new(atomic.Int64),
+	}
+}
+
+// WithAssociations returns a copy of the factory, that also builds the given associations for every model.
+// Associations are resolved before overrides are applied, so overrides can still replace the linked values.
+func (factory *Factory[T]) WithAssociations(associations ...Association[T]) *Factory[T] {
+	return &Factory[T]{
+		defaults:     factory.defaults,
+		associations: append(append([]Association[T]{}, factory.associations...), associations...),
+		sequence:     factory.sequence,
+	}
+}
+
+func (factory *Factory[T]) newModel() *T {
+	return factory.defaults(int(factory.sequence.Add(1)))
+}
+
+func applyOverrides[T any](model *T, overrides []func(model *T)) *T {
+	for _, override := range overrides {
+		override(model)
+	}
+
+	return model
+}
+
+// Build returns a new model, without inserting it. Associated records are built, but not inserted either.
+func (factory *Factory[T]) Build(overrides ...func(model *T)) *T {
+	model := factory.newModel()
+
+	for _, assoc := range factory.associations {
+		assoc.build(model)
+	}
+
+	return applyOverrides(model, overrides)
+}
+
+// BuildMany returns n new models, without inserting them. Overrides apply to every model.
+func (factory *Factory[T]) BuildMany(n int, overrides ...func(model *T)) []*T {
+	models := make([]*T, n)
+	for i := range models {
+		models[i] = factory.Build(overrides...)
+	}
+
+	return models
+}
+
+func (factory *Factory[T]) prepare(ctx context.Context, database bun.IDB, overrides []func(model *T)) (*T, error) {
+	model := factory.newModel()
+
+	for _, assoc := range factory.associations {
+		if err := assoc.create(ctx, database, model); err != nil {
+			return nil, fmt.Errorf("create association: %w", err)
+		}
+	}
+
+	return applyOverrides(model, overrides), nil
+}
+
+// Create builds a new model, and inserts it along with its associated records.
+func (factory *Factory[T]) Create(ctx context.Context, database bun.IDB, overrides ...func(model *T)) (*T, error) {
+	model, err := factory.prepare(ctx, database, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = InsertFixtures(ctx, database, model); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// CreateMany builds n new models, and inserts them in a single statement, after their associated records.
+// Overrides apply to every model.
+func (factory *Factory[T]) CreateMany(
+	ctx context.Context, database bun.IDB, n int, overrides ...func(model *T),
+) ([]*T, error) {
+	models := make([]*T, n)
+	fixtures := make([]any, n)
+
+	for i := range models {
+		model, err := factory.prepare(ctx, database, overrides)
+		if err != nil {
+			return nil, err
+		}
+
+		models[i] = model
+		fixtures[i] = model
+	}
+
+	if err := InsertFixtures(ctx, database, fixtures...); err != nil {
+		return nil, err
+	}
+
+	return models, nil
+}
@@ -0,0 +1,73 @@
+package asqltest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func newTable1Factory() *asqltest.Factory[databasemocks.Table1Model] {
+	return asqltest.NewFactory(func(seq int) *databasemocks.Table1Model {
+		return &databasemocks.Table1Model{ID: int64(seq), Name: fmt.Sprintf("table1-%d", seq)}
+	})
+}
+
+func TestFactoryBuild(t *testing.T) {
+	table1 := newTable1Factory()
+
+	require.Equal(t, &databasemocks.Table1Model{ID: 1, Name: "table1-1"}, table1.Build())
+	require.Equal(t, &databasemocks.Table1Model{ID: 2, Name: "foo"}, table1.Build(func(model *databasemocks.Table1Model) {
+		model.Name = "foo"
+	}))
+	require.Equal(t, []*databasemocks.Table1Model{
+		{ID: 3, Name: "table1-3"},
+		{ID: 4, Name: "table1-4"},
+	}, table1.BuildMany(2))
+
+	table2 := asqltest.NewFactory(func(seq int) *databasemocks.Table2Model {
+		return &databasemocks.Table2Model{ID: int64(seq)}
+	}).WithAssociations(asqltest.Associate(table1, func(model *databasemocks.Table2Model, associated *databasemocks.Table1Model) {
+		model.Name = associated.Name
+	}))
+
+	require.Equal(t, &databasemocks.Table2Model{ID: 1, Name: "table1-5"}, table2.Build())
+}
+
+func TestFactoryCreate(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+	tx := asqltest.NewTx(t, db)
+	ctx := context.Background()
+
+	table1 := newTable1Factory()
+	table2 := asqltest.NewFactory(func(seq int) *databasemocks.Table2Model {
+		return &databasemocks.Table2Model{ID: int64(seq)}
+	}).WithAssociations(asqltest.Associate(table1, func(model *databasemocks.Table2Model, associated *databasemocks.Table1Model) {
+		model.Name = associated.Name
+	}))
+
+	created, err := table2.Create(ctx, tx)
+	require.NoError(t, err)
+	require.Equal(t, &databasemocks.Table2Model{ID: 1, Name: "table1-1"}, created)
+
+	many, err := table2.CreateMany(ctx, tx, 2)
+	require.NoError(t, err)
+	require.Len(t, many, 2)
+
+	count, err := tx.NewSelect().Model((*databasemocks.Table1Model)(nil)).Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	count, err = tx.NewSelect().Model((*databasemocks.Table2Model)(nil)).Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	// Duplicate primary key.
+	_, err = table1.Create(ctx, tx, func(model *databasemocks.Table1Model) { model.ID = 1 })
+	var fixtureErr *asqltest.FixtureError
+	require.ErrorAs(t, err, &fixtureErr)
+}
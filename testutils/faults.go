@@ -0,0 +1,530 @@
+package asqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"github.com/uptrace/bun/schema"
+)
+
+// SQLSTATE codes of the errors most commonly handled by applications. See
+// https://www.postgresql.org/docs/current/errcodes-appendix.html for the full list.
+const (
+	SQLStateUniqueViolation      = "23505"
+	SQLStateForeignKeyViolation  = "23503"
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlockDetected     = "40P01"
+	SQLStateQueryCanceled        = "57014"
+	SQLStateConnectionFailure    = "08006"
+	SQLStateInternalError        = "XX000"
+)
+
+// Fault describes an error or a delay, injected by a FaultyDB in the queries it runs.
+type Fault struct {
+	// Pattern is a regular expression, matched against the SQL sent to the database, with the arguments of the
+	// query formatted in. An empty pattern matches every query. Transactions are matched as a "BEGIN" query.
+	Pattern string
+	// Call restricts the fault to the Nth query matching the pattern, starting at 1. With 0, the fault applies to
+	// every matching query.
+	Call int
+
+	// Latency delays the query. It is applied before the error, if any.
+	Latency time.Duration
+
+	// SQLState makes the query fail with a Postgres error of the given code. The error is raised by the database
+	// itself, so it is a genuine pgdriver.Error, and aborts the current transaction like a real failure would.
+	SQLState string
+	// Message of the Postgres error. Defaults to "injected fault".
+	Message string
+
+	// Err makes the query fail with the given error, without reaching the database. It is ignored when SQLState
+	// is set. Use driver.ErrBadConn to simulate a connection drop.
+	Err error
+}
+
+type faultRule struct {
+	Fault
+
+	matcher *regexp.Regexp
+	calls   int
+}
+
+// Raise a Postgres error from the database. The statement is formatted locally, with the formatter of the dialect,
+// and wrapped in an anonymous code block.
+const raiseFaultQuery = `RAISE EXCEPTION USING ERRCODE = ?, MESSAGE = ?;`
+
+// Return the code block that raises the given error. The block is quoted with a dollar tag that does not appear in
+// its body, so any message can be used.
+func raiseFaultSQL(dialect schema.Dialect, code, message string) string {
+	if message == "" {
+		message = "injected fault"
+	}
+
+	body := schema.NewFormatter(dialect).FormatQuery(raiseFaultQuery, code, message)
+
+	tag := "$fault$"
+	for i := 0; strings.Contains(body, tag); i++ {
+		tag = fmt.Sprintf("$fault%d$", i)
+	}
+
+	return "DO " + tag + " BEGIN " + body + " END " + tag
+}
+
+// Wait for the given duration, unless the context is canceled first.
+func waitFault(ctx context.Context, latency time.Duration) error {
+	if latency <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// FaultyDB wraps a bun.IDB, and injects faults in the queries that run through it. Queries built from a FaultyDB
+// (NewSelect, NewInsert, NewRaw, ...) go through it as well.
+//
+// Transactions started from a FaultyDB created by NewFaultyDB are not wrapped: bun transactions send their queries
+// to the database directly. Use OpenFaultyDB to inject faults in the queries of transactions too, for example to
+// test retries of a RunInTx callback.
+//
+//	faulty := asqltest.NewFaultyDB(tx, asqltest.Fault{
+//		Pattern:  `^UPDATE "accounts"`,
+//		Call:     1,
+//		SQLState: asqltest.SQLStateSerializationFailure,
+//	})
+//
+//	// The first update fails, the retry succeeds.
+//	err := service.Transfer(ctx, faulty, from, to, amount)
+type FaultyDB struct {
+	bun.IDB
+
+	// Connection used by the queries built from this wrapper. It goes through faults before reaching the database.
+	conn *faultyConn
+	// Faults are injected by the driver of the database (see OpenFaultyDB), so the wrapper must not inject them
+	// again.
+	inDriver bool
+
+	rules []*faultRule
+	mu    sync.Mutex
+}
+
+var _ bun.IDB = (*FaultyDB)(nil)
+
+// NewFaultyDB wraps a bun.IDB, and injects the given faults in its queries.
+func NewFaultyDB(db bun.IDB, faults ...Fault) *FaultyDB {
+	faulty := &FaultyDB{IDB: db}
+	faulty.conn = &faultyConn{db: faulty, raw: rawConn(db)}
+
+	for _, fault := range faults {
+		faulty.Inject(fault)
+	}
+
+	return faulty
+}
+
+// Inject adds a fault. Calls that happened before are not counted by Fault.Call.
+func (db *FaultyDB) Inject(fault Fault) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.rules = append(db.rules, &faultRule{
+		Fault:   fault,
+		matcher: regexp.MustCompile(fault.Pattern),
+	})
+}
+
+// Clear removes every fault.
+func (db *FaultyDB) Clear() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.rules = nil
+}
+
+// Return the faults that apply to the given query, and count the call.
+func (db *FaultyDB) match(query string) []Fault {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var output []Fault
+
+	for _, rule := range db.rules {
+		if !rule.matcher.MatchString(query) {
+			continue
+		}
+
+		rule.calls++
+
+		if rule.Call == 0 || rule.Call == rule.calls {
+			output = append(output, rule.Fault)
+		}
+	}
+
+	return output
+}
+
+// Apply the faults that match the query. It returns the error to report, if any. Postgres errors are raised by
+// running the given statement on the connection of the query.
+func (db *FaultyDB) inject(ctx context.Context, query string, raise func(statement string) error) error {
+	for _, fault := range db.match(query) {
+		if err := waitFault(ctx, fault.Latency); err != nil {
+			return err
+		}
+
+		if fault.SQLState != "" {
+			return raise(raiseFaultSQL(db.Dialect(), fault.SQLState, fault.Message))
+		}
+
+		if fault.Err != nil {
+			return fault.Err
+		}
+	}
+
+	return nil
+}
+
+// Apply the faults of a query sent through the wrapper.
+func (db *FaultyDB) apply(ctx context.Context, conn bun.IConn, query string) error {
+	if db.inDriver {
+		return nil
+	}
+
+	return db.inject(ctx, query, func(statement string) error {
+		_, err := conn.ExecContext(ctx, statement)
+		return err
+	})
+}
+
+// Format a query the way bun does before sending it, so patterns are matched against the same SQL whether the
+// query is built by bun or sent with raw arguments.
+func (db *FaultyDB) format(query string, args []any) string {
+	if len(args) == 0 {
+		return query
+	}
+
+	if formatter, ok := db.IDB.(interface{ Formatter() schema.Formatter }); ok {
+		return formatter.Formatter().FormatQuery(query, args...)
+	}
+
+	return schema.NewFormatter(db.Dialect()).FormatQuery(query, args...)
+}
+
+// QueryRowContext cannot return an arbitrary error, so Fault.Err is reported as an internal error (XX000) raised by
+// the database, with the message of the error. If the context is canceled while waiting, the query is sent with it,
+// so the row reports the cancellation.
+func (db *FaultyDB) queryRow(
+	ctx context.Context, conn bun.IConn, next bun.IConn, query string, args ...any,
+) *sql.Row {
+	if db.inDriver {
+		return next.QueryRowContext(ctx, query, args...)
+	}
+
+	for _, fault := range db.match(db.format(query, args)) {
+		if waitFault(ctx, fault.Latency) != nil {
+			break
+		}
+
+		code, message := fault.SQLState, fault.Message
+		if code == "" && fault.Err != nil {
+			code, message = SQLStateInternalError, fault.Err.Error()
+		}
+
+		if code != "" {
+			return conn.QueryRowContext(ctx, raiseFaultSQL(db.Dialect(), code, message))
+		}
+	}
+
+	return next.QueryRowContext(ctx, query, args...)
+}
+
+func (db *FaultyDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := db.apply(ctx, db.conn.raw, db.format(query, args)); err != nil {
+		return nil, err
+	}
+
+	return db.IDB.ExecContext(ctx, query, args...)
+}
+
+func (db *FaultyDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := db.apply(ctx, db.conn.raw, db.format(query, args)); err != nil {
+		return nil, err
+	}
+
+	return db.IDB.QueryContext(ctx, query, args...)
+}
+
+func (db *FaultyDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.queryRow(ctx, db.conn.raw, db.IDB, query, args...)
+}
+
+func (db *FaultyDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (bun.Tx, error) {
+	if err := db.apply(ctx, db.conn.raw, "BEGIN"); err != nil {
+		return bun.Tx{}, err
+	}
+
+	return db.IDB.BeginTx(ctx, opts)
+}
+
+func (db *FaultyDB) RunInTx(
+	ctx context.Context, opts *sql.TxOptions, f func(ctx context.Context, tx bun.Tx) error,
+) error {
+	if err := db.apply(ctx, db.conn.raw, "BEGIN"); err != nil {
+		return err
+	}
+
+	return db.IDB.RunInTx(ctx, opts, f)
+}
+
+func (db *FaultyDB) NewValues(model any) *bun.ValuesQuery {
+	return db.IDB.NewValues(model).Conn(db.conn)
+}
+
+func (db *FaultyDB) NewSelect() *bun.SelectQuery {
+	return db.IDB.NewSelect().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewInsert() *bun.InsertQuery {
+	return db.IDB.NewInsert().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewUpdate() *bun.UpdateQuery {
+	return db.IDB.NewUpdate().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewDelete() *bun.DeleteQuery {
+	return db.IDB.NewDelete().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewMerge() *bun.MergeQuery {
+	return db.IDB.NewMerge().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewRaw(query string, args ...any) *bun.RawQuery {
+	return db.IDB.NewRaw(query, args...).Conn(db.conn)
+}
+
+func (db *FaultyDB) NewCreateTable() *bun.CreateTableQuery {
+	return db.IDB.NewCreateTable().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewDropTable() *bun.DropTableQuery {
+	return db.IDB.NewDropTable().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewCreateIndex() *bun.CreateIndexQuery {
+	return db.IDB.NewCreateIndex().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewDropIndex() *bun.DropIndexQuery {
+	return db.IDB.NewDropIndex().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewTruncateTable() *bun.TruncateTableQuery {
+	return db.IDB.NewTruncateTable().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewAddColumn() *bun.AddColumnQuery {
+	return db.IDB.NewAddColumn().Conn(db.conn)
+}
+
+func (db *FaultyDB) NewDropColumn() *bun.DropColumnQuery {
+	return db.IDB.NewDropColumn().Conn(db.conn)
+}
+
+// Return the database/sql connection behind a bun connection. Queries built by bun are already formatted, and
+// have run their hooks, so they are sent to it directly, like bun does.
+func rawConn(db bun.IDB) bun.IConn {
+	switch typed := db.(type) {
+	case *bun.DB:
+		return typed.DB
+	case bun.Conn:
+		return typed.Conn
+	case bun.Tx:
+		return typed.Tx
+	case *FaultyDB:
+		return typed.conn
+	default:
+		return db
+	}
+}
+
+// faultyConn is the connection of the queries built from a FaultyDB.
+type faultyConn struct {
+	db  *FaultyDB
+	raw bun.IConn
+}
+
+func (conn *faultyConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := conn.db.apply(ctx, conn.raw, query); err != nil {
+		return nil, err
+	}
+
+	return conn.raw.ExecContext(ctx, query, args...)
+}
+
+func (conn *faultyConn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := conn.db.apply(ctx, conn.raw, query); err != nil {
+		return nil, err
+	}
+
+	return conn.raw.QueryContext(ctx, query, args...)
+}
+
+func (conn *faultyConn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return conn.db.queryRow(ctx, conn.raw, conn.raw, query, args...)
+}
+
+// OpenFaultyDB opens a connection to the database of the DSN, and injects the given faults in its queries. Unlike
+// NewFaultyDB, faults are injected by the driver, so they also apply to the queries of the transactions started
+// from it, including savepoints and RunInTx callbacks. Statements prepared explicitly are not matched.
+//
+// Queries that fail with driver.ErrBadConn outside a transaction are retried by database/sql on another
+// connection, so each attempt counts as a call.
+//
+// It returns the database, along with a function that closes it.
+func OpenFaultyDB(dsn string, faults ...Fault) (*FaultyDB, func(), error) {
+	connector := &faultyConnector{Connector: pgdriver.NewConnector(pgdriver.WithDSN(dsn))}
+	sqldb := sql.OpenDB(connector)
+	database := bun.NewDB(sqldb, pgdialect.New())
+
+	closer := func() {
+		_ = database.Close()
+	}
+
+	if err := database.Ping(); err != nil {
+		closer()
+		return nil, nil, fmt.Errorf("ping database: %w", err)
+	}
+
+	faulty := NewFaultyDB(database, faults...)
+	faulty.inDriver = true
+	connector.db = faulty
+
+	return faulty, closer, nil
+}
+
+// faultyConnector opens connections that inject the faults of a FaultyDB.
+type faultyConnector struct {
+	driver.Connector
+
+	db *FaultyDB
+}
+
+func (connector *faultyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := connector.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyDriverConn{Conn: conn, connector: connector}, nil
+}
+
+// faultyDriverConn injects faults in the queries of a driver connection. Queries sent by bun are already formatted,
+// so they are matched as they are.
+type faultyDriverConn struct {
+	driver.Conn
+
+	connector *faultyConnector
+}
+
+// Apply the faults that match the query. Faults are ignored until the connector is bound to its FaultyDB.
+func (conn *faultyDriverConn) apply(ctx context.Context, query string) error {
+	db := conn.connector.db
+	if db == nil {
+		return nil
+	}
+
+	return db.inject(ctx, query, func(statement string) error {
+		execer, ok := conn.Conn.(driver.ExecerContext)
+		if !ok {
+			return fmt.Errorf("driver connection %T cannot raise errors", conn.Conn)
+		}
+
+		_, err := execer.ExecContext(ctx, statement, nil)
+
+		return err
+	})
+}
+
+func (conn *faultyDriverConn) ExecContext(
+	ctx context.Context, query string, args []driver.NamedValue,
+) (driver.Result, error) {
+	execer, ok := conn.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	if err := conn.apply(ctx, query); err != nil {
+		return nil, err
+	}
+
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (conn *faultyDriverConn) QueryContext(
+	ctx context.Context, query string, args []driver.NamedValue,
+) (driver.Rows, error) {
+	queryer, ok := conn.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	if err := conn.apply(ctx, query); err != nil {
+		return nil, err
+	}
+
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (conn *faultyDriverConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := conn.apply(ctx, "BEGIN"); err != nil {
+		return nil, err
+	}
+
+	if beginner, ok := conn.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	//nolint:staticcheck // Fallback for drivers that do not support contexts.
+	return conn.Conn.Begin()
+}
+
+func (conn *faultyDriverConn) Ping(ctx context.Context) error {
+	if pinger, ok := conn.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (conn *faultyDriverConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := conn.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (conn *faultyDriverConn) IsValid() bool {
+	if validator, ok := conn.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+
+	return true
+}
@@ -0,0 +1,147 @@
+package asqltest_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestFaultyDB(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+	ctx := context.Background()
+
+	t.Run("SQLState", func(t *testing.T) {
+		tx := asqltest.NewTx(t, db)
+		faulty := asqltest.NewFaultyDB(tx, asqltest.Fault{
+			Pattern:  `^INSERT INTO "table1"`,
+			Call:     2,
+			SQLState: asqltest.SQLStateSerializationFailure,
+		})
+
+		_, err := faulty.NewInsert().Model(&databasemocks.Table1Model{ID: 1, Name: "foo"}).Exec(ctx)
+		require.NoError(t, err)
+
+		_, err = faulty.NewInsert().Model(&databasemocks.Table1Model{ID: 2, Name: "bar"}).Exec(ctx)
+
+		var pgErr pgdriver.Error
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, asqltest.SQLStateSerializationFailure, pgErr.Field('C'))
+		require.Equal(t, "injected fault", pgErr.Field('M'))
+	})
+
+	t.Run("Message", func(t *testing.T) {
+		// Messages can contain anything, including the delimiters of code blocks.
+		message := `it's $$ a $fault$ message`
+		faulty := asqltest.NewFaultyDB(db, asqltest.Fault{
+			SQLState: asqltest.SQLStateUniqueViolation,
+			Message:  message,
+		})
+
+		_, err := faulty.ExecContext(ctx, "SELECT 1")
+
+		var pgErr pgdriver.Error
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, asqltest.SQLStateUniqueViolation, pgErr.Field('C'))
+		require.Equal(t, message, pgErr.Field('M'))
+
+		var value int
+		err = faulty.QueryRowContext(ctx, "SELECT 1").Scan(&value)
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, message, pgErr.Field('M'))
+	})
+
+	t.Run("FormattedArguments", func(t *testing.T) {
+		// Patterns are matched against the query with its arguments, however it was sent.
+		faulty := asqltest.NewFaultyDB(db, asqltest.Fault{Pattern: `'target'`, Err: driver.ErrBadConn})
+
+		_, err := faulty.ExecContext(ctx, "SELECT ?", "target")
+		require.ErrorIs(t, err, driver.ErrBadConn)
+
+		_, err = faulty.NewRaw("SELECT ?", "target").Exec(ctx)
+		require.ErrorIs(t, err, driver.ErrBadConn)
+
+		_, err = faulty.ExecContext(ctx, "SELECT ?", "other")
+		require.NoError(t, err)
+	})
+
+	t.Run("Err", func(t *testing.T) {
+		faulty := asqltest.NewFaultyDB(db, asqltest.Fault{Err: driver.ErrBadConn})
+
+		var models []*databasemocks.Table1Model
+		require.ErrorIs(t, faulty.NewSelect().Model(&models).Scan(ctx), driver.ErrBadConn)
+
+		_, err := faulty.BeginTx(ctx, nil)
+		require.ErrorIs(t, err, driver.ErrBadConn)
+
+		faulty.Clear()
+		require.NoError(t, faulty.NewSelect().Model(&models).Scan(ctx))
+	})
+
+	t.Run("Latency", func(t *testing.T) {
+		faulty := asqltest.NewFaultyDB(db, asqltest.Fault{Pattern: `^SELECT`, Latency: 100 * time.Millisecond})
+
+		start := time.Now()
+
+		var models []*databasemocks.Table1Model
+		require.NoError(t, faulty.NewSelect().Model(&models).Scan(ctx))
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, faulty.NewSelect().Model(&models).Scan(timeoutCtx), context.DeadlineExceeded)
+
+		// Single row queries stop waiting as well.
+		start = time.Now()
+
+		var value int
+		require.ErrorIs(t, faulty.QueryRowContext(timeoutCtx, "SELECT 1").Scan(&value), context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		faulty, closer, err := asqltest.OpenFaultyDB(asqltest.GetTestDSN(), asqltest.Fault{
+			Pattern:  `^INSERT INTO "table1"`,
+			Call:     1,
+			SQLState: asqltest.SQLStateSerializationFailure,
+		})
+		require.NoError(t, err)
+		t.Cleanup(closer)
+
+		errRollback := errors.New("rollback")
+		insert := func(ctx context.Context, tx bun.Tx) error {
+			_, err := tx.NewInsert().Model(&databasemocks.Table1Model{ID: 10, Name: "foo"}).Exec(ctx)
+			if err != nil {
+				return err
+			}
+
+			// Leave the shared database untouched.
+			return errRollback
+		}
+
+		// Queries of transactions go through the faults as well: the first attempt fails, the retry succeeds.
+		err = faulty.RunInTx(ctx, nil, insert)
+
+		var pgErr pgdriver.Error
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, asqltest.SQLStateSerializationFailure, pgErr.Field('C'))
+
+		require.ErrorIs(t, faulty.RunInTx(ctx, nil, insert), errRollback)
+
+		// Beginning a transaction can fail too.
+		faulty.Inject(asqltest.Fault{Pattern: "^BEGIN$", SQLState: asqltest.SQLStateDeadlockDetected})
+
+		_, err = faulty.BeginTx(ctx, nil)
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, asqltest.SQLStateDeadlockDetected, pgErr.Field('C'))
+	})
+}
@@ -0,0 +1,138 @@
+package asqltest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/uptrace/bun"
+)
+
+// FixtureError is returned when a fixture cannot be inserted.
+//
+// Consecutive fixtures of the same type are inserted in a single statement. When this statement fails, the
+// fixtures are inserted again one by one, to find the faulty one. The error only covers the whole batch if none of
+// them fails on its own.
+type FixtureError struct {
+	// Index of the faulty fixture, or of the first fixture of the failing batch, in the list of fixtures.
+	Index int
+	// Count is the number of fixtures the error covers. It is 1 once the faulty fixture is found.
+	Count int
+	// Type of the fixtures in the batch.
+	Type reflect.Type
+
+	Err error
+}
+
+func (err *FixtureError) Error() string {
+	if err.Count > 1 {
+		return fmt.Sprintf("insert fixtures %v to %v (%s): %v", err.Index, err.Index+err.Count-1, err.Type, err.Err)
+	}
+
+	return fmt.Sprintf("insert fixture %v (%s): %v", err.Index, err.Type, err.Err)
+}
+
+func (err *FixtureError) Unwrap() error {
+	return err.Err
+}
+
+type fixturesBatch struct {
+	index int
+	count int
+	model any
+}
+
+// Group consecutive fixtures of the same type, so they can be inserted in bulk. Slices are inserted on their own.
+func batchFixtures(fixtures []any) []fixturesBatch {
+	var batches []fixturesBatch
+
+	for i := 0; i < len(fixtures); i++ {
+		fixtureType := reflect.TypeOf(fixtures[i])
+
+		// Slices are already a bulk insert, and cannot be merged with other fixtures.
+		if fixtureType == nil || fixtureType.Kind() == reflect.Slice {
+			batches = append(batches, fixturesBatch{index: i, count: 1, model: fixtures[i]})
+			continue
+		}
+
+		end := i + 1
+		for end < len(fixtures) && reflect.TypeOf(fixtures[end]) == fixtureType {
+			end++
+		}
+
+		if end-i == 1 {
+			batches = append(batches, fixturesBatch{index: i, count: 1, model: fixtures[i]})
+			continue
+		}
+
+		bulk := reflect.MakeSlice(reflect.SliceOf(fixtureType), 0, end-i)
+		for _, fixture := range fixtures[i:end] {
+			bulk = reflect.Append(bulk, reflect.ValueOf(fixture))
+		}
+
+		// Bun expects a pointer to a slice for bulk inserts.
+		bulkPtr := reflect.New(bulk.Type())
+		bulkPtr.Elem().Set(bulk)
+
+		batches = append(batches, fixturesBatch{index: i, count: end - i, model: bulkPtr.Interface()})
+		i = end - 1
+	}
+
+	return batches
+}
+
+// Insert the fixtures of a failed batch one by one, and return the position of the first that fails, along with its
+// error. It returns -1 if every fixture can be inserted on its own. Fixtures are inserted in a transaction (or a
+// savepoint) that is always rolled back, so the database is left as it was.
+func locateFaultyFixture(ctx context.Context, database bun.IDB, fixtures []any) (int, error) {
+	transaction, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, nil
+	}
+	defer RollbackTestTX(transaction)
+
+	for i, fixture := range fixtures {
+		if _, err = transaction.NewInsert().Model(fixture).Exec(ctx); err != nil {
+			return i, err
+		}
+	}
+
+	return -1, nil
+}
+
+// InsertFixtures inserts fixtures of any type, in the given order. Models can be passed as pointers, or as slices
+// for explicit bulk inserts. Consecutive fixtures of the same type are inserted in a single statement.
+//
+// Fixtures that depend on others (through foreign keys, for example) must be passed after them.
+//
+// On failure, a *FixtureError is returned, that points to the faulty fixture. Each batch is inserted in its own
+// transaction (or savepoint, if database is a transaction), so a failure does not abort the enclosing transaction.
+func InsertFixtures(ctx context.Context, database bun.IDB, fixtures ...any) error {
+	for _, batch := range batchFixtures(fixtures) {
+		err := database.RunInTx(ctx, nil, func(ctx context.Context, transaction bun.Tx) error {
+			_, err := transaction.NewInsert().Model(batch.model).Exec(ctx)
+			return err
+		})
+		if err == nil {
+			continue
+		}
+
+		fixtureErr := &FixtureError{
+			Index: batch.index,
+			Count: batch.count,
+			Type:  reflect.TypeOf(fixtures[batch.index]),
+			Err:   err,
+		}
+
+		if batch.count > 1 {
+			batchFixtures := fixtures[batch.index : batch.index+batch.count]
+			if position, positionErr := locateFaultyFixture(ctx, database, batchFixtures); position >= 0 {
+				fixtureErr.Index, fixtureErr.Count, fixtureErr.Err = batch.index+position, 1, positionErr
+			}
+		}
+
+		return fixtureErr
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package asqltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Return the bun model registered for a table, if any. Tables can be qualified by their schema.
+func fixtureTable(database bun.IDB, name string) *schema.Table {
+	tables := database.Dialect().Tables()
+
+	if table := tables.ByName(name); table != nil {
+		return table
+	}
+
+	schemaName, tableName, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil
+	}
+
+	if table := tables.ByName(tableName); table != nil && table.Schema == schemaName {
+		return table
+	}
+
+	return nil
+}
+
+// fixtureTime prints as RFC3339, so it can be parsed back by YAML and Postgres.
+type fixtureTime time.Time
+
+func (t fixtureTime) String() string {
+	return time.Time(t).Format(time.RFC3339Nano)
+}
+
+// Functions available in fixture files.
+var fixtureFuncs = template.FuncMap{
+	"now": func() fixtureTime {
+		return fixtureTime(time.Now().UTC())
+	},
+	// Usage: {{ now | add "-24h" }}. The duration uses the format of time.ParseDuration.
+	"add": func(duration string, t fixtureTime) (fixtureTime, error) {
+		parsed, err := time.ParseDuration(duration)
+		if err != nil {
+			return t, err
+		}
+
+		return fixtureTime(time.Time(t).Add(parsed)), nil
+	},
+	"uuid": func() string {
+		return uuid.NewString()
+	},
+}
+
+type fixtureTableRows struct {
+	table string
+	rows  []map[string]any
+}
+
+// Render the template of a fixture file, and parse it. Tables are returned in the order of the file.
+func parseFixtureFile(name string, content []byte) ([]fixtureTableRows, error) {
+	tmpl, err := template.New(name).Funcs(fixtureFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	var rendered bytes.Buffer
+	if err = tmpl.Execute(&rendered, nil); err != nil {
+		return nil, fmt.Errorf("render template: %w", err)
+	}
+
+	// JSON is a subset of YAML, so both formats are parsed the same way. Nodes preserve the order of the tables,
+	// which matters for foreign keys.
+	var document yaml.Node
+	if err = yaml.Unmarshal(rendered.Bytes(), &document); err != nil {
+		return nil, fmt.Errorf("parse fixtures: %w", err)
+	}
+
+	// Empty file.
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("fixtures must be a mapping of table names to rows (line %d)", root.Line)
+	}
+
+	output := make([]fixtureTableRows, 0, len(root.Content)/2)
+
+	for i := 0; i < len(root.Content); i += 2 {
+		entry := fixtureTableRows{table: root.Content[i].Value}
+		if err = root.Content[i+1].Decode(&entry.rows); err != nil {
+			return nil, fmt.Errorf("decode rows of %s: %w", entry.table, err)
+		}
+
+		output = append(output, entry)
+	}
+
+	return output, nil
+}
+
+// Convert a value decoded from YAML into one of the types returned by database drivers, so it can be handled
+// by bun scanners and formatters. Nested values are encoded as JSON.
+func normalizeFixtureValue(value any) (any, error) {
+	switch typed := value.(type) {
+	case int:
+		return int64(typed), nil
+	case uint64:
+		return typed, nil
+	case []any, map[string]any:
+		return json.Marshal(typed)
+	default:
+		return value, nil
+	}
+}
+
+// Decode a row into a new instance of the model of the table.
+func decodeFixtureRow(table *schema.Table, row map[string]any) (any, error) {
+	model := reflect.New(table.Type)
+	strct := model.Elem()
+
+	for column, value := range row {
+		field, err := table.Field(column)
+		if err != nil {
+			return nil, err
+		}
+
+		switch value.(type) {
+		case []any, map[string]any:
+			// Bun scanners expect the raw representation of the database, e.g. Postgres arrays. Nested values
+			// map more naturally to Go types.
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("encode column %s: %w", column, err)
+			}
+
+			if err = json.Unmarshal(encoded, field.Value(strct).Addr().Interface()); err != nil {
+				return nil, fmt.Errorf("decode column %s: %w", column, err)
+			}
+		default:
+			normalized, err := normalizeFixtureValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("decode column %s: %w", column, err)
+			}
+
+			if err = field.ScanValue(strct, normalized); err != nil {
+				return nil, fmt.Errorf("decode column %s: %w", column, err)
+			}
+		}
+	}
+
+	return model.Interface(), nil
+}
+
+func insertFixtureRows(ctx context.Context, database bun.IDB, entry fixtureTableRows) error {
+	if table := fixtureTable(database, entry.table); table != nil {
+		models := make([]any, len(entry.rows))
+
+		for i, row := range entry.rows {
+			model, err := decodeFixtureRow(table, row)
+			if err != nil {
+				return fmt.Errorf("row %d: %w", i, err)
+			}
+
+			models[i] = model
+		}
+
+		return InsertFixtures(ctx, database, models...)
+	}
+
+	for i, row := range entry.rows {
+		values := make(map[string]any, len(row))
+
+		for column, value := range row {
+			normalized, err := normalizeFixtureValue(value)
+			if err != nil {
+				return fmt.Errorf("row %d: encode column %s: %w", i, column, err)
+			}
+
+			// Bun would format raw bytes as bytea.
+			if encoded, ok := normalized.([]byte); ok {
+				normalized = string(encoded)
+			}
+
+			values[column] = normalized
+		}
+
+		if _, err := database.NewInsert().Model(&values).TableExpr("?", bun.Ident(entry.table)).Exec(ctx); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+const listSerialColumnsQuery = `
+SELECT att.attname
+FROM pg_catalog.pg_attribute att
+WHERE att.attrelid = ?0::regclass
+  AND att.attnum > 0
+  AND NOT att.attisdropped
+  AND pg_catalog.pg_get_serial_sequence(?0, att.attname) IS NOT NULL;
+`
+
+// Move the sequences of a table past the highest value inserted, so rows created by the tested code do not
+// collide with fixtures that set their IDs explicitly. The change of a sequence is not undone by a rollback.
+func resetSequences(ctx context.Context, database bun.IDB, table string) error {
+	var columns []string
+	if err := database.NewRaw(listSerialColumnsQuery, table).Scan(ctx, &columns); err != nil {
+		return fmt.Errorf("list serial columns: %w", err)
+	}
+
+	for _, column := range columns {
+		_, err := database.ExecContext(
+			ctx,
+			"SELECT setval(pg_catalog.pg_get_serial_sequence(?, ?), COALESCE(MAX(?), 0) + 1, false) FROM ?",
+			table, column, bun.Ident(column), bun.Ident(table),
+		)
+		if err != nil {
+			return fmt.Errorf("reset sequence of %s: %w", column, err)
+		}
+	}
+
+	return nil
+}
+
+// LoadFixtures inserts the rows described in the given files, in order. Files are YAML or JSON documents, that map
+// table names to lists of rows:
+//
+//	users:
+//	  - id: 1
+//	    name: john
+//	    created_at: {{ now | add "-24h" }}
+//	posts:
+//	  - id: '{{ uuid }}'
+//	    user_id: 1
+//
+// Tables are inserted in the order they appear in, so tables that reference others must come after them.
+//
+// Files are rendered as text/template before being parsed. The following functions are available:
+//   - now: the current time.
+//   - add: shifts a time by a duration, as parsed by time.ParseDuration.
+//   - uuid: a random UUID (v4).
+//
+// Rows of tables with a model registered with bun (see bun.DB.RegisterModel, or any query already run on the model)
+// are decoded into this model. Other rows are inserted as raw columns, and nested values (lists and mappings) are
+// encoded as JSON.
+//
+// Once every row is inserted, the sequences of the tables are moved past the highest value inserted. Sequences are
+// not transactional: when fixtures are loaded in a transaction, the sequences stay advanced after it is rolled back.
+func LoadFixtures(ctx context.Context, database bun.IDB, fsys fs.FS, files ...string) error {
+	var (
+		touched     []string
+		touchedSeen = make(map[string]bool)
+	)
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", file, err)
+		}
+
+		entries, err := parseFixtureFile(file, content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, entry := range entries {
+			if err = insertFixtureRows(ctx, database, entry); err != nil {
+				return fmt.Errorf("%s: table %s: %w", file, entry.table, err)
+			}
+
+			if !touchedSeen[entry.table] {
+				touchedSeen[entry.table] = true
+				touched = append(touched, entry.table)
+			}
+		}
+	}
+
+	for _, table := range touched {
+		if err := resetSequences(ctx, database, table); err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package asqltest_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+
+	// Rows of table1 are decoded into its model. table2 and table3 have no registered model, and are inserted as
+	// raw columns.
+	db.RegisterModel((*databasemocks.Table1Model)(nil))
+
+	tx := asqltest.NewTx(t, db)
+	ctx := context.Background()
+
+	require.NoError(t, asqltest.LoadFixtures(ctx, tx, os.DirFS("testdata/fixtures"), "tables.yaml", "table3.json"))
+
+	var table1 []*databasemocks.Table1Model
+	require.NoError(t, tx.NewSelect().Model(&table1).Order("id").Scan(ctx))
+	require.Len(t, table1, 2)
+
+	_, err := uuid.Parse(table1[0].Name)
+	require.NoError(t, err)
+
+	yesterday, err := time.Parse(time.RFC3339Nano, table1[1].Name)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(-24*time.Hour), yesterday, time.Minute)
+
+	var table3 []*databasemocks.Table3Model
+	require.NoError(t, tx.NewSelect().Model(&table3).Scan(ctx))
+	require.Equal(t, []*databasemocks.Table3Model{{ID: 5, Name: "qux"}}, table3)
+
+	// Sequences are moved past the fixtures.
+	created := &databasemocks.Table2Model{Name: "new"}
+	_, err = tx.NewInsert().Model(created).ExcludeColumn("id").Returning("id").Exec(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), created.ID)
+}
@@ -0,0 +1,85 @@
+package asqltest_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestInsertFixtures(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+
+	t.Run("Heterogeneous", func(t *testing.T) {
+		tx := asqltest.NewTx(t, db)
+
+		require.NoError(t, asqltest.InsertFixtures(context.Background(), tx,
+			&databasemocks.Table1Model{ID: 1, Name: "foo"},
+			&databasemocks.Table1Model{ID: 2, Name: "bar"},
+			&databasemocks.Table2Model{ID: 1, Name: "baz"},
+			[]*databasemocks.Table3Model{{ID: 1, Name: "qux"}, {ID: 2, Name: "quux"}},
+		))
+
+		count, err := tx.NewSelect().Model((*databasemocks.Table1Model)(nil)).Count(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		count, err = tx.NewSelect().Model((*databasemocks.Table2Model)(nil)).Count(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		count, err = tx.NewSelect().Model((*databasemocks.Table3Model)(nil)).Count(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		tx := asqltest.NewTx(t, db)
+
+		err := asqltest.InsertFixtures(context.Background(), tx,
+			&databasemocks.Table2Model{ID: 1, Name: "baz"},
+			&databasemocks.Table1Model{ID: 1, Name: "foo"},
+			// Duplicate primary key.
+			&databasemocks.Table1Model{ID: 1, Name: "bar"},
+		)
+
+		var fixtureErr *asqltest.FixtureError
+		require.ErrorAs(t, err, &fixtureErr)
+		// The fixtures are inserted in bulk, but the error points to the faulty one.
+		require.Equal(t, 2, fixtureErr.Index)
+		require.Equal(t, 1, fixtureErr.Count)
+		require.Equal(t, reflect.TypeOf(&databasemocks.Table1Model{}), fixtureErr.Type)
+
+		// The transaction is still usable, and the rows of the failed batch were not inserted.
+		count, err := tx.NewSelect().Model((*databasemocks.Table1Model)(nil)).Count(context.Background())
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+}
+
+func TestBeginTestTXPanics(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+
+	defer func() {
+		recovered := recover()
+		require.NotNil(t, recovered)
+
+		fixtureErr, ok := recovered.(*asqltest.FixtureError)
+		require.True(t, ok)
+		require.Equal(t, 3, fixtureErr.Index)
+		require.Equal(t, 1, fixtureErr.Count)
+		require.Equal(t, reflect.TypeOf(&databasemocks.Table1Model{}), fixtureErr.Type)
+	}()
+
+	asqltest.BeginTestTX(db, []any{
+		&databasemocks.Table2Model{ID: 1, Name: "baz"},
+		&databasemocks.Table1Model{ID: 1, Name: "foo"},
+		&databasemocks.Table2Model{ID: 2, Name: "qux"},
+		// Duplicate primary key.
+		&databasemocks.Table1Model{ID: 1, Name: "bar"},
+	})
+}
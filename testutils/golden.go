@@ -0,0 +1,226 @@
+package asqltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+)
+
+// UpdateGoldenFlag is the test flag that regenerates golden files: go test ./... -update.
+const UpdateGoldenFlag = "update"
+
+// UpdateGoldenEnv is the environment variable that regenerates golden files when set to a non-empty value, for
+// runs where the flag cannot be passed.
+const UpdateGoldenEnv = "ASQL_UPDATE_GOLDEN"
+
+// The flag is only registered if no other package did, so the flag of an imported package is used instead of
+// conflicting with it. Test packages that need the value should read it with flag.Lookup, rather than defining
+// the flag again.
+func init() {
+	if flag.Lookup(UpdateGoldenFlag) == nil {
+		flag.Bool(UpdateGoldenFlag, false, "update golden files")
+	}
+}
+
+// Return the value of the -update flag, whichever package registered it.
+func updateGoldenFlag() bool {
+	registered := flag.Lookup(UpdateGoldenFlag)
+	if registered == nil {
+		return false
+	}
+
+	if getter, ok := registered.Value.(flag.Getter); ok {
+		value, ok := getter.Get().(bool)
+		return ok && value
+	}
+
+	return registered.Value.String() == "true"
+}
+
+// Value that replaces masked columns in snapshots.
+const maskedValue = "<masked>"
+
+type snapshotConfig struct {
+	maskedColumns []string
+	dir           string
+	update        bool
+}
+
+// SnapshotOption customizes table snapshots.
+type SnapshotOption func(config *snapshotConfig)
+
+// WithMaskedColumns replaces the values of volatile columns, like generated IDs or timestamps, in snapshots.
+// Columns are given either as "column", to mask them in every table, or as "table.column". NULL values are not
+// masked, so snapshots still tell them apart.
+func WithMaskedColumns(columns ...string) SnapshotOption {
+	return func(config *snapshotConfig) {
+		config.maskedColumns = append(config.maskedColumns, columns...)
+	}
+}
+
+// WithGoldenDir sets the directory of golden files. Defaults to "testdata".
+func WithGoldenDir(dir string) SnapshotOption {
+	return func(config *snapshotConfig) {
+		config.dir = dir
+	}
+}
+
+// WithUpdateGolden writes the golden files, instead of comparing them, when update is true. It lets test packages
+// wire a flag of their own:
+//
+//	var regenerate = flag.Bool("regenerate", false, "regenerate golden files")
+//
+//	asqltest.AssertSnapshot(t, db, "users", tables, asqltest.WithUpdateGolden(*regenerate))
+func WithUpdateGolden(update bool) SnapshotOption {
+	return func(config *snapshotConfig) {
+		config.update = config.update || update
+	}
+}
+
+func newSnapshotConfig(opts []SnapshotOption) *snapshotConfig {
+	config := &snapshotConfig{dir: "testdata", update: updateGoldenFlag() || os.Getenv(UpdateGoldenEnv) != ""}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return config
+}
+
+func (config *snapshotConfig) isMasked(table, column string) bool {
+	for _, masked := range config.maskedColumns {
+		if masked == column || masked == table+"."+column {
+			return true
+		}
+	}
+
+	return false
+}
+
+const listPrimaryKeyColumnsQuery = `
+SELECT att.attname
+FROM pg_catalog.pg_index ind
+JOIN pg_catalog.pg_attribute att ON att.attrelid = ind.indrelid AND att.attnum = ANY(ind.indkey)
+WHERE ind.indrelid = ?::regclass AND ind.indisprimary
+ORDER BY array_position(ind.indkey, att.attnum);
+`
+
+func snapshotTable(ctx context.Context, db bun.IDB, table string, config *snapshotConfig) ([]map[string]any, error) {
+	var primaryKey []string
+	if err := db.NewRaw(listPrimaryKeyColumnsQuery, table).Scan(ctx, &primaryKey); err != nil {
+		return nil, fmt.Errorf("list primary key: %w", err)
+	}
+
+	// Tables without a primary key are ordered by the text representation of their rows, that is always
+	// comparable.
+	order := bun.SafeQuery("row_data::text")
+	if len(primaryKey) > 0 {
+		idents := make([]any, len(primaryKey))
+		for i, column := range primaryKey {
+			idents[i] = bun.Ident(column)
+		}
+
+		order = bun.SafeQuery(strings.TrimSuffix(strings.Repeat("?, ", len(idents)), ", "), idents...)
+	}
+
+	var rows []json.RawMessage
+	if err := db.NewRaw(
+		"SELECT to_jsonb(row_data) FROM ? AS row_data ORDER BY ?", bun.Ident(table), order,
+	).Scan(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("read rows: %w", err)
+	}
+
+	output := make([]map[string]any, len(rows))
+
+	for i, row := range rows {
+		// Keep numbers as is, large integers do not fit in a float64.
+		decoder := json.NewDecoder(bytes.NewReader(row))
+		decoder.UseNumber()
+
+		if err := decoder.Decode(&output[i]); err != nil {
+			return nil, fmt.Errorf("decode row %d: %w", i, err)
+		}
+
+		for column, value := range output[i] {
+			if value != nil && config.isMasked(table, column) {
+				output[i][column] = maskedValue
+			}
+		}
+	}
+
+	return output, nil
+}
+
+// SnapshotTables returns the content of the given tables, as indented JSON. Rows are ordered by primary key, and
+// columns by name, so snapshots of the same data are identical.
+func SnapshotTables(ctx context.Context, db bun.IDB, tables []string, opts ...SnapshotOption) ([]byte, error) {
+	config := newSnapshotConfig(opts)
+
+	snapshot := make(map[string][]map[string]any, len(tables))
+
+	for _, table := range tables {
+		rows, err := snapshotTable(ctx, db, table, config)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", table, err)
+		}
+
+		snapshot[table] = rows
+	}
+
+	// Maps are encoded with sorted keys.
+	output, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	return append(output, '\n'), nil
+}
+
+// AssertSnapshot compares the content of the given tables with the golden file "<name>.golden.json", in the
+// testdata directory. On failure, it prints a diff between the golden file and the actual content.
+//
+// Run the tests with the -update flag (or ASQL_UPDATE_GOLDEN=1, or use WithUpdateGolden) to write the golden files,
+// instead of comparing them.
+func AssertSnapshot(t testing.TB, db bun.IDB, name string, tables []string, opts ...SnapshotOption) bool {
+	t.Helper()
+
+	config := newSnapshotConfig(opts)
+
+	actual, err := SnapshotTables(context.Background(), db, tables, opts...)
+	if err != nil {
+		t.Errorf("%v", err)
+		return false
+	}
+
+	path := filepath.Join(config.dir, name+".golden.json")
+
+	if config.update {
+		if err = os.MkdirAll(config.dir, 0o755); err != nil {
+			t.Errorf("create golden directory: %v", err)
+			return false
+		}
+
+		if err = os.WriteFile(path, actual, 0o644); err != nil {
+			t.Errorf("write golden file: %v", err)
+			return false
+		}
+
+		return true
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("read golden file (run with ASQL_UPDATE_GOLDEN=1 to create it): %v", err)
+		return false
+	}
+
+	return assert.Equal(t, string(expected), string(actual), "snapshot %s", path)
+}
@@ -0,0 +1,62 @@
+package asqltest_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestAssertSnapshot(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+	tx := asqltest.NewTx(t, db,
+		&databasemocks.Table1Model{ID: 2, Name: "bar"},
+		&databasemocks.Table1Model{ID: 1, Name: "foo"},
+		&databasemocks.Table2Model{ID: 1, Name: "baz"},
+	)
+
+	dir := t.TempDir()
+	tables := []string{"table1", "table2", "table3"}
+	opts := []asqltest.SnapshotOption{asqltest.WithGoldenDir(dir), asqltest.WithMaskedColumns("table2.name")}
+
+	// The golden file does not exist yet.
+	require.False(t, asqltest.AssertSnapshot(new(testing.T), tx, "tables", tables, opts...))
+
+	t.Setenv(asqltest.UpdateGoldenEnv, "1")
+	require.True(t, asqltest.AssertSnapshot(t, tx, "tables", tables, opts...))
+
+	golden, err := os.ReadFile(filepath.Join(dir, "tables.golden.json"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"table1": [{"id": 1, "name": "foo"}, {"id": 2, "name": "bar"}],
+		"table2": [{"id": 1, "name": "<masked>"}],
+		"table3": []
+	}`, string(golden))
+
+	t.Setenv(asqltest.UpdateGoldenEnv, "")
+	require.True(t, asqltest.AssertSnapshot(t, tx, "tables", tables, opts...))
+
+	_, err = tx.NewUpdate().Model(&databasemocks.Table1Model{ID: 1, Name: "qux"}).WherePK().Exec(context.Background())
+	require.NoError(t, err)
+	require.False(t, asqltest.AssertSnapshot(new(testing.T), tx, "tables", tables, opts...))
+
+	// Golden files are updated with the -update flag.
+	require.NoError(t, flag.Set(asqltest.UpdateGoldenFlag, "true"))
+	t.Cleanup(func() { _ = flag.Set(asqltest.UpdateGoldenFlag, "false") })
+	require.True(t, asqltest.AssertSnapshot(t, tx, "tables", tables, opts...))
+	require.NoError(t, flag.Set(asqltest.UpdateGoldenFlag, "false"))
+	require.True(t, asqltest.AssertSnapshot(t, tx, "tables", tables, opts...))
+
+	_, err = tx.NewUpdate().Model(&databasemocks.Table1Model{ID: 1, Name: "quux"}).WherePK().Exec(context.Background())
+	require.NoError(t, err)
+
+	// Golden files can be updated from a flag of the test package.
+	require.True(t, asqltest.AssertSnapshot(t, tx, "tables", tables, append(opts, asqltest.WithUpdateGolden(true))...))
+	require.True(t, asqltest.AssertSnapshot(t, tx, "tables", tables, opts...))
+}
@@ -0,0 +1,264 @@
+package asqltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// PostgresBinDirEnv is the environment variable that points to the directory of the Postgres binaries (initdb,
+// pg_ctl). If not set, the binaries are looked up in the PATH, then in the usual installation directories.
+const PostgresBinDirEnv = "ASQL_PG_BIN_DIR"
+
+var ErrPostgresBinaryNotFound = errors.New("postgres binary not found")
+
+// Credentials of the superuser created by initdb.
+const (
+	localPostgresUser     = "test"
+	localPostgresPassword = "test"
+	localPostgresDatabase = "postgres"
+)
+
+type localPostgresConfig struct {
+	binDir       string
+	startTimeout time.Duration
+}
+
+// LocalPostgresOption customizes the server started by StartLocalPostgres.
+type LocalPostgresOption func(config *localPostgresConfig)
+
+// WithPostgresBinDir sets the directory of the Postgres binaries. It takes precedence over ASQL_PG_BIN_DIR.
+func WithPostgresBinDir(dir string) LocalPostgresOption {
+	return func(config *localPostgresConfig) {
+		config.binDir = dir
+	}
+}
+
+// WithStartTimeout sets how long to wait for the server to accept connections. Defaults to 30 seconds.
+func WithStartTimeout(timeout time.Duration) LocalPostgresOption {
+	return func(config *localPostgresConfig) {
+		config.startTimeout = timeout
+	}
+}
+
+// LocalPostgres is a throwaway Postgres server, run from the binaries installed on the machine. Its data lives in a
+// temporary directory, that is removed when the server stops.
+type LocalPostgres struct {
+	dir   string
+	pgCtl string
+	dsn   string
+}
+
+// Find a Postgres binary. Distributions like Debian do not put them in the PATH, so the versioned installation
+// directories are searched as well, most recent version first.
+func findPostgresBinary(binDir string, name string) (string, error) {
+	if binDir != "" {
+		path := filepath.Join(binDir, name)
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("%w: %s", ErrPostgresBinaryNotFound, path)
+		}
+
+		return path, nil
+	}
+
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+
+	candidates, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql", "*", "bin", name))
+	candidates = append(candidates, filepath.Join("/usr/local/opt/postgresql", "bin", name))
+	sort.Sort(sort.Reverse(sort.StringSlice(candidates)))
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrPostgresBinaryNotFound, name)
+}
+
+// Reserve a free TCP port. The port is released before Postgres binds to it, so another process could take it in
+// between, but this is unlikely in practice.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func runPostgresCommand(name string, args ...string) error {
+	var output bytes.Buffer
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w\n%s", filepath.Base(name), err, output.String())
+	}
+
+	return nil
+}
+
+// StartLocalPostgres initializes a new Postgres cluster in a temporary directory, and starts it on a random port.
+// It returns once the server accepts connections.
+//
+// initdb refuses to run as root, so tests using a local server must run as a regular user.
+func StartLocalPostgres(opts ...LocalPostgresOption) (*LocalPostgres, error) {
+	config := &localPostgresConfig{
+		binDir:       os.Getenv(PostgresBinDirEnv),
+		startTimeout: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	initDB, err := findPostgresBinary(config.binDir, "initdb")
+	if err != nil {
+		return nil, err
+	}
+
+	pgCtl, err := findPostgresBinary(config.binDir, "pg_ctl")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "asql-postgres-")
+	if err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+
+	server := &LocalPostgres{dir: dir, pgCtl: pgCtl}
+
+	if err = server.start(initDB, config.startTimeout); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return server, nil
+}
+
+func (server *LocalPostgres) start(initDB string, timeout time.Duration) error {
+	passwordFile := filepath.Join(server.dir, "password")
+	if err := os.WriteFile(passwordFile, []byte(localPostgresPassword), 0o600); err != nil {
+		return fmt.Errorf("write password file: %w", err)
+	}
+
+	// The server only listens on the loopback interface, and is destroyed with the tests: durability is not needed.
+	err := runPostgresCommand(
+		initDB,
+		"--pgdata", server.dataDir(),
+		"--username", localPostgresUser,
+		"--pwfile", passwordFile,
+		"--auth", "scram-sha-256",
+		"--encoding", "UTF8",
+		"--no-sync",
+	)
+	if err != nil {
+		return err
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return fmt.Errorf("find free port: %w", err)
+	}
+
+	// The unix socket is created in the temporary directory, so it does not conflict with a system-wide server.
+	serverOptions := fmt.Sprintf(
+		"-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off -c synchronous_commit=off -c full_page_writes=off",
+		port, server.dir,
+	)
+
+	err = runPostgresCommand(
+		server.pgCtl,
+		"start",
+		"--pgdata", server.dataDir(),
+		"--log", filepath.Join(server.dir, "postgres.log"),
+		"--options", serverOptions,
+		"--wait",
+		"--timeout", strconv.Itoa(int(timeout.Seconds())),
+	)
+	if err != nil {
+		return err
+	}
+
+	server.dsn = (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(localPostgresUser, localPostgresPassword),
+		Host:     net.JoinHostPort("127.0.0.1", strconv.Itoa(port)),
+		Path:     "/" + localPostgresDatabase,
+		RawQuery: "sslmode=disable",
+	}).String()
+
+	return nil
+}
+
+func (server *LocalPostgres) dataDir() string {
+	return filepath.Join(server.dir, "data")
+}
+
+// DSN returns the DSN of the server. The connected role is a superuser.
+func (server *LocalPostgres) DSN() string {
+	return server.dsn
+}
+
+// Stop shuts down the server, and removes its data.
+func (server *LocalPostgres) Stop() error {
+	stopErr := runPostgresCommand(
+		server.pgCtl, "stop", "--pgdata", server.dataDir(), "--mode", "immediate", "--wait",
+	)
+
+	if err := os.RemoveAll(server.dir); err != nil {
+		return errors.Join(stopErr, fmt.Errorf("remove data directory: %w", err))
+	}
+
+	return stopErr
+}
+
+// RunWithLocalPostgres runs the tests of a package against a local Postgres server, started for the occasion. It
+// is meant to be called from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(asqltest.RunWithLocalPostgres(m))
+//	}
+//
+// The server DSN is exposed through ASQL_TEST_DSN, so every helper of this package uses it. If ASQL_TEST_DSN is
+// already set, no server is started, and the tests run against the configured database.
+func RunWithLocalPostgres(m *testing.M, opts ...LocalPostgresOption) int {
+	if os.Getenv(TestDSNEnv) != "" {
+		return m.Run()
+	}
+
+	server, err := StartLocalPostgres(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "start local postgres: %v\n", err)
+		return 1
+	}
+
+	defer func() {
+		if err := server.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "stop local postgres: %v\n", err)
+		}
+	}()
+
+	if err = os.Setenv(TestDSNEnv, server.DSN()); err != nil {
+		fmt.Fprintf(os.Stderr, "expose local postgres dsn: %v\n", err)
+		return 1
+	}
+	defer os.Unsetenv(TestDSNEnv)
+
+	return m.Run()
+}
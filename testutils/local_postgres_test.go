@@ -0,0 +1,38 @@
+package asqltest_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/asql"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestStartLocalPostgres(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("initdb cannot run as root")
+	}
+
+	server, err := asqltest.StartLocalPostgres()
+	if errors.Is(err, asqltest.ErrPostgresBinaryNotFound) {
+		t.Skip(err.Error())
+	}
+	require.NoError(t, err)
+
+	database, closer, err := asql.OpenDB(server.DSN())
+	require.NoError(t, err)
+
+	var result int
+	require.NoError(t, database.NewRaw("SELECT 1").Scan(context.Background(), &result))
+	require.Equal(t, 1, result)
+
+	closer()
+	require.NoError(t, server.Stop())
+
+	_, _, err = asql.OpenDB(server.DSN())
+	require.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package asqltest
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// RecordedQuery is a query captured by a QueryRecorder.
+type RecordedQuery struct {
+	// Query is the SQL sent to the database, with arguments formatted in.
+	Query string
+	// Template is the query before arguments are formatted in, when available.
+	Template  string
+	Args      []any
+	Operation string
+	Duration  time.Duration
+	// RowsAffected is -1 when the driver does not report it, or if the query failed.
+	RowsAffected int64
+	Err          error
+}
+
+// QueryRecorder captures the queries sent through a bun.DB, including those of its transactions and
+// connections. Create it with NewQueryRecorder.
+type QueryRecorder struct {
+	queries []RecordedQuery
+	stopped bool
+
+	mu sync.Mutex
+}
+
+var _ bun.QueryHook = (*QueryRecorder)(nil)
+
+func (recorder *QueryRecorder) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+func (recorder *QueryRecorder) AfterQuery(_ context.Context, event *bun.QueryEvent) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if recorder.stopped {
+		return
+	}
+
+	query := RecordedQuery{
+		Query:        event.Query,
+		Template:     event.QueryTemplate,
+		Args:         event.QueryArgs,
+		Operation:    event.Operation(),
+		Duration:     time.Since(event.StartTime),
+		RowsAffected: -1,
+		Err:          event.Err,
+	}
+
+	if event.Err == nil && event.Result != nil {
+		if rowsAffected, err := event.Result.RowsAffected(); err == nil {
+			query.RowsAffected = rowsAffected
+		}
+	}
+
+	recorder.queries = append(recorder.queries, query)
+}
+
+// NewQueryRecorder starts recording the queries sent through the given database. Recording stops when the test
+// completes, and the recorded queries are logged if the test failed.
+//
+// Bun hooks cannot be removed, so the recorder stays installed on the database, but inactive. Queries from other
+// tests that share the database while the recorder is active are recorded as well: use a dedicated database (see
+// WithIsolation) for tests that run in parallel.
+func NewQueryRecorder(t testing.TB, db *bun.DB) *QueryRecorder {
+	t.Helper()
+
+	recorder := new(QueryRecorder)
+	db.AddQueryHook(recorder)
+
+	t.Cleanup(func() {
+		recorder.Stop()
+
+		if t.Failed() {
+			t.Logf("recorded queries:\n%s", recorder.Dump())
+		}
+	})
+
+	return recorder
+}
+
+// Stop ends the recording. Queries recorded so far are kept.
+func (recorder *QueryRecorder) Stop() {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.stopped = true
+}
+
+// Reset discards the queries recorded so far, for example to ignore the setup of a test.
+func (recorder *QueryRecorder) Reset() {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.queries = nil
+}
+
+// Queries returns the recorded queries, in the order they completed.
+func (recorder *QueryRecorder) Queries() []RecordedQuery {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return append([]RecordedQuery(nil), recorder.queries...)
+}
+
+// QueriesMatching returns the recorded queries whose SQL matches the given regular expression.
+func (recorder *QueryRecorder) QueriesMatching(pattern string) []RecordedQuery {
+	matcher := regexp.MustCompile(pattern)
+
+	var output []RecordedQuery
+
+	for _, query := range recorder.Queries() {
+		if matcher.MatchString(query.Query) {
+			output = append(output, query)
+		}
+	}
+
+	return output
+}
+
+func dumpQueries(queries []RecordedQuery) string {
+	if len(queries) == 0 {
+		return "(no query)\n"
+	}
+
+	var output strings.Builder
+
+	for i, query := range queries {
+		fmt.Fprintf(&output, "%d. [%s, %s", i+1, query.Operation, query.Duration.Round(time.Microsecond))
+
+		if query.RowsAffected >= 0 {
+			fmt.Fprintf(&output, ", %d rows", query.RowsAffected)
+		}
+
+		if query.Err != nil {
+			fmt.Fprintf(&output, ", error: %v", query.Err)
+		}
+
+		fmt.Fprintf(&output, "] %s\n", strings.TrimSpace(query.Query))
+	}
+
+	return output.String()
+}
+
+// Dump returns a readable list of the recorded queries.
+func (recorder *QueryRecorder) Dump() string {
+	return dumpQueries(recorder.Queries())
+}
+
+// AssertQueryCount checks the number of recorded queries.
+func (recorder *QueryRecorder) AssertQueryCount(t testing.TB, expected int) bool {
+	t.Helper()
+
+	queries := recorder.Queries()
+	if len(queries) != expected {
+		t.Errorf("expected %d queries, got %d:\n%s", expected, len(queries), dumpQueries(queries))
+		return false
+	}
+
+	return true
+}
+
+// AssertQueryCountMatching checks the number of recorded queries whose SQL matches the given regular expression.
+func (recorder *QueryRecorder) AssertQueryCountMatching(t testing.TB, pattern string, expected int) bool {
+	t.Helper()
+
+	queries := recorder.QueriesMatching(pattern)
+	if len(queries) != expected {
+		t.Errorf(
+			"expected %d queries matching %q, got %d:\n%s",
+			expected, pattern, len(queries), dumpQueries(recorder.Queries()),
+		)
+
+		return false
+	}
+
+	return true
+}
+
+// AssertNoQueryMatching checks that no recorded query matches the given regular expression.
+func (recorder *QueryRecorder) AssertNoQueryMatching(t testing.TB, pattern string) bool {
+	t.Helper()
+
+	if queries := recorder.QueriesMatching(pattern); len(queries) > 0 {
+		t.Errorf("expected no query matching %q, got %d:\n%s", pattern, len(queries), dumpQueries(queries))
+		return false
+	}
+
+	return true
+}
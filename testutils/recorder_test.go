@@ -0,0 +1,49 @@
+package asqltest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestQueryRecorder(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+	ctx := context.Background()
+
+	recorder := asqltest.NewQueryRecorder(t, db)
+	tx := asqltest.NewTx(t, db)
+
+	// Ignore the setup.
+	recorder.Reset()
+
+	_, err := tx.NewInsert().Model(&[]*databasemocks.Table1Model{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}).Exec(ctx)
+	require.NoError(t, err)
+
+	var models []*databasemocks.Table1Model
+	require.NoError(t, tx.NewSelect().Model(&models).Scan(ctx))
+
+	queries := recorder.Queries()
+	require.Len(t, queries, 2)
+	require.Equal(t, "INSERT", queries[0].Operation)
+	require.Equal(t, int64(2), queries[0].RowsAffected)
+	require.Equal(t, "SELECT", queries[1].Operation)
+
+	require.True(t, recorder.AssertQueryCount(t, 2))
+	require.True(t, recorder.AssertQueryCountMatching(t, `^INSERT INTO "table1"`, 1))
+	require.True(t, recorder.AssertNoQueryMatching(t, `DELETE`))
+
+	mockT := new(testing.T)
+	require.False(t, recorder.AssertQueryCount(mockT, 1))
+	require.False(t, recorder.AssertQueryCountMatching(mockT, `^SELECT`, 2))
+	require.False(t, recorder.AssertNoQueryMatching(mockT, `^SELECT`))
+
+	require.Contains(t, recorder.Dump(), `2. [SELECT`)
+
+	recorder.Stop()
+	require.NoError(t, tx.NewSelect().Model(&models).Scan(ctx))
+	require.Len(t, recorder.Queries(), 2)
+}
@@ -0,0 +1,267 @@
+package asqltest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/a-novel-kit/asql"
+)
+
+// Prefixes of the databases created by OpenIsolatedTestDB.
+const (
+	templateDBPrefix = "asql_template_"
+	isolatedDBPrefix = "asql_test_"
+)
+
+// Templates that were not used for this long are dropped, so templates of outdated migrations do not accumulate.
+const staleTemplateAge = 7 * 24 * time.Hour
+
+// The last time a template was used is stored in its comment. The comment is only written once the template is
+// migrated, so templates without one are still being created by another process, and are never stale.
+const listStaleTemplatesQuery = `
+SELECT datname
+FROM pg_catalog.pg_database
+WHERE datname LIKE 'asql\_template\_%'
+  AND datname <> ?
+  AND pg_catalog.shobj_description(oid, 'pg_database') IS NOT NULL
+  AND pg_catalog.shobj_description(oid, 'pg_database') < ?;
+`
+
+// Keep track of the templates already prepared by this process, to skip the lookup on subsequent calls.
+var (
+	preparedTemplates   = make(map[string]bool)
+	preparedTemplatesMu sync.Mutex
+)
+
+// Compute a checksum of every file in the migrations file system. Any change in the migrations results in a new
+// template database.
+func migrationsChecksum(sqlMigrations fs.FS) ([]byte, error) {
+	hash := sha256.New()
+
+	if sqlMigrations == nil {
+		return hash.Sum(nil), nil
+	}
+
+	// WalkDir visits files in lexical order, so the checksum is deterministic.
+	err := fs.WalkDir(sqlMigrations, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(sqlMigrations, path)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+
+		hash.Write([]byte(path))
+		hash.Write([]byte{0})
+		hash.Write(content)
+		hash.Write([]byte{0})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// Return the DSN of another database, on the same server as the test database.
+func dsnWithDatabase(dsn string, database string) (string, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("parse dsn: %w", err)
+	}
+
+	parsed.Path = "/" + database
+
+	return parsed.String(), nil
+}
+
+// Create the template database for the given migrations, unless it already exists.
+func prepareTemplateDB(
+	ctx context.Context, admin *bun.DB, template string, lockID int64, sqlMigrations fs.FS,
+) error {
+	preparedTemplatesMu.Lock()
+	defer preparedTemplatesMu.Unlock()
+
+	if preparedTemplates[template] {
+		return nil
+	}
+
+	// Tests from other packages run in separate processes, that may try to create the same template concurrently.
+	// A session-level advisory lock serializes them, so it must be acquired and released on the same connection.
+	conn, err := admin.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock(?)", lockID); err != nil {
+		return fmt.Errorf("lock template: %w", err)
+	}
+	defer func() {
+		_, _ = conn.ExecContext(ctx, "SELECT pg_advisory_unlock(?)", lockID)
+	}()
+
+	var exists bool
+	if err = conn.NewRaw("SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = ?)", template).
+		Scan(ctx, &exists); err != nil {
+		return fmt.Errorf("look for template: %w", err)
+	}
+
+	if !exists {
+		if err = createTemplateDB(ctx, conn, template, sqlMigrations); err != nil {
+			return err
+		}
+	}
+
+	if _, err = conn.ExecContext(
+		ctx, "COMMENT ON DATABASE ? IS ?", bun.Ident(template), time.Now().UTC().Format(time.RFC3339),
+	); err != nil {
+		return fmt.Errorf("mark template as used: %w", err)
+	}
+
+	dropStaleTemplates(ctx, conn, template)
+
+	preparedTemplates[template] = true
+
+	return nil
+}
+
+// Drop the templates that were not used recently. Timestamps are stored in RFC3339 format, in UTC, so they compare
+// as strings. Errors are ignored: a template that is being cloned cannot be dropped, and will be on a later run.
+func dropStaleTemplates(ctx context.Context, conn bun.Conn, current string) {
+	var templates []string
+	if err := conn.NewRaw(
+		listStaleTemplatesQuery, current, time.Now().Add(-staleTemplateAge).UTC().Format(time.RFC3339),
+	).Scan(ctx, &templates); err != nil {
+		return
+	}
+
+	for _, template := range templates {
+		_, _ = conn.ExecContext(ctx, "DROP DATABASE IF EXISTS ?", bun.Ident(template))
+	}
+}
+
+func createTemplateDB(ctx context.Context, conn bun.Conn, template string, sqlMigrations fs.FS) error {
+	if _, err := conn.ExecContext(ctx, "CREATE DATABASE ?", bun.Ident(template)); err != nil {
+		return fmt.Errorf("create template: %w", err)
+	}
+
+	// Remove the template if it could not be migrated, so the next run starts over.
+	dropTemplate := func() {
+		_, _ = conn.ExecContext(ctx, "DROP DATABASE IF EXISTS ? WITH (FORCE)", bun.Ident(template))
+	}
+
+	if sqlMigrations == nil {
+		return nil
+	}
+
+	templateDSN, err := dsnWithDatabase(GetTestDSN(), template)
+	if err != nil {
+		dropTemplate()
+		return err
+	}
+
+	database, closer, err := asql.OpenDB(templateDSN)
+	if err != nil {
+		dropTemplate()
+		return fmt.Errorf("open template: %w", err)
+	}
+
+	_, err = asql.MigrateWithSink(database, sqlMigrations, asql.NewNopMigrationSink())
+	// Postgres cannot clone a database that has open connections.
+	closer()
+
+	if err != nil {
+		dropTemplate()
+		return fmt.Errorf("migrate template: %w", err)
+	}
+
+	return nil
+}
+
+// OpenIsolatedTestDB opens a connection to a brand-new database, dedicated to the current test. The database is
+// dropped when the test completes. The test fails immediately if the database cannot be created.
+//
+// Databases are cloned from a template, that has the given migrations applied. Templates are created once per
+// set of migrations, so only the first test that uses a given set pays the cost of migrating. Unlike OpenTestDB,
+// tests using isolated databases can run in parallel, including across packages. Templates that were not used
+// for a week are dropped.
+//
+// The server hosting the test DB must allow the test user to create databases.
+func OpenIsolatedTestDB(t testing.TB, sqlMigrations fs.FS) *bun.DB {
+	t.Helper()
+
+	ctx := context.Background()
+
+	checksum, err := migrationsChecksum(sqlMigrations)
+	if err != nil {
+		t.Fatalf("compute migrations checksum: %v", err)
+	}
+
+	admin, closeAdmin, err := asql.OpenDB(GetTestDSN())
+	if err != nil {
+		t.Fatalf("open admin db: %v", err)
+	}
+	defer closeAdmin()
+
+	// 8 bytes of the checksum are enough to tell migration sets apart, and fit in an advisory lock key.
+	template := templateDBPrefix + hex.EncodeToString(checksum[:8])
+	lockID := int64(binary.BigEndian.Uint64(checksum[:8]))
+
+	if err = prepareTemplateDB(ctx, admin, template, lockID, sqlMigrations); err != nil {
+		t.Fatalf("prepare template: %v", err)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err = rand.Read(suffix); err != nil {
+		t.Fatalf("generate database name: %v", err)
+	}
+
+	name := isolatedDBPrefix + hex.EncodeToString(suffix)
+	if _, err = admin.ExecContext(ctx, "CREATE DATABASE ? TEMPLATE ?", bun.Ident(name), bun.Ident(template)); err != nil {
+		t.Fatalf("clone template: %v", err)
+	}
+
+	// Registered first, so it runs last: the connection is closed before the database is dropped.
+	t.Cleanup(func() {
+		admin, closeAdmin, err := asql.OpenDB(GetTestDSN())
+		if err != nil {
+			t.Errorf("open admin db to drop %s: %v", name, err)
+			return
+		}
+		defer closeAdmin()
+
+		if _, err = admin.ExecContext(ctx, "DROP DATABASE IF EXISTS ? WITH (FORCE)", bun.Ident(name)); err != nil {
+			t.Errorf("drop %s: %v", name, err)
+		}
+	})
+
+	dsn, err := dsnWithDatabase(GetTestDSN(), name)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	database, closer, err := asql.OpenDB(dsn)
+	if err != nil {
+		t.Fatalf("open isolated db: %v", err)
+	}
+
+	t.Cleanup(closer)
+
+	return database
+}
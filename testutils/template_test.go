@@ -0,0 +1,72 @@
+package asqltest_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	databasemocks "github.com/a-novel-kit/asql/mocks/migrations"
+	asqltest "github.com/a-novel-kit/asql/testutils"
+)
+
+func TestOpenIsolatedTestDB(t *testing.T) {
+	for _, name := range []string{"First", "Second", "Third"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db := asqltest.OpenIsolatedTestDB(t, databasemocks.MigrationsAll)
+
+			// Every database starts empty, with migrations applied.
+			count, err := db.NewSelect().Model((*databasemocks.Table1Model)(nil)).Count(context.Background())
+			require.NoError(t, err)
+			require.Zero(t, count)
+
+			_, err = db.NewInsert().
+				Model(&databasemocks.Table1Model{ID: 1, Name: name}).
+				Exec(context.Background())
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("NoMigrations", func(t *testing.T) {
+		db := asqltest.OpenIsolatedTestDB(t, nil)
+
+		_, err := db.Exec("SELECT 1")
+		require.NoError(t, err)
+	})
+}
+
+func TestOpenIsolatedTestDBDropsStaleTemplates(t *testing.T) {
+	admin := asqltest.NewDB(t)
+
+	_, err := admin.Exec("DROP DATABASE IF EXISTS asql_template_stale")
+	require.NoError(t, err)
+	_, err = admin.Exec("CREATE DATABASE asql_template_stale")
+	require.NoError(t, err)
+	_, err = admin.Exec("COMMENT ON DATABASE asql_template_stale IS '2000-01-01T00:00:00Z'")
+	require.NoError(t, err)
+
+	// Templates without a comment are still being migrated by another process.
+	_, err = admin.Exec("DROP DATABASE IF EXISTS asql_template_pending")
+	require.NoError(t, err)
+	_, err = admin.Exec("CREATE DATABASE asql_template_pending")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, _ = admin.Exec("DROP DATABASE IF EXISTS asql_template_pending")
+	})
+
+	// A new set of migrations needs a new template, which triggers the cleanup.
+	asqltest.OpenIsolatedTestDB(t, fstest.MapFS{
+		"20200101120000_migration.up.sql":   {Data: []byte("CREATE TABLE stale_templates (id SERIAL PRIMARY KEY);")},
+		"20200101120000_migration.down.sql": {Data: []byte("DROP TABLE stale_templates;")},
+	})
+
+	var templates []string
+	require.NoError(t, admin.NewRaw(
+		"SELECT datname FROM pg_database WHERE datname IN ('asql_template_stale', 'asql_template_pending')",
+	).Scan(context.Background(), &templates))
+	require.Equal(t, []string{"asql_template_pending"}, templates)
+}
@@ -2,49 +2,62 @@ package asqltest
 
 import (
 	"context"
-	"fmt"
+	"testing"
 	"time"
 
 	"github.com/uptrace/bun"
 )
 
-const setFakeNowFn = `
-CREATE SCHEMA IF NOT EXISTS override;
-
-GRANT ALL ON SCHEMA override TO public;
-GRANT ALL ON SCHEMA override TO test;
+// FreezeTime freezes the database time at the given date, and returns the handle that queries must go through to
+// see it. It is a shorthand for a frozen Clock: see Clock for the overridden functions.
+//
+// On a *bun.DB, a connection is reserved from the pool, like NewConnClock, so the frozen time does not leak to the
+// other connections. On a bun.Conn or a bun.Tx, the clock is bound to it, and the handle is the given db.
+func FreezeTime(db bun.IDB, date time.Time) (*Clock, bun.IDB, error) {
+	// https://stackoverflow.com/questions/48243934/mocking-postgresql-now-function-for-testing
+	if pool, ok := db.(*bun.DB); ok {
+		clock, conn, err := NewConnClock(context.Background(), pool, date)
+		if err != nil {
+			return nil, nil, err
+		}
 
-CREATE OR REPLACE FUNCTION override.now() 
-  RETURNS timestamptz IMMUTABLE PARALLEL SAFE AS 
-$$
-BEGIN
-    return ?0::timestamptz;
-END
-$$ language plpgsql;
+		return clock, conn, nil
+	}
 
-set search_path = override,pg_temp,"$user",public,pg_catalog;
-`
+	clock, err := NewClock(db, date)
+	if err != nil {
+		return nil, nil, err
+	}
 
-const unsetFakeNowFn = `
-DROP FUNCTION IF EXISTS override.now();
-set search_path = pg_temp,"$user",public,pg_catalog;
-`
+	return clock, db, nil
+}
 
-func FreezeTime(db bun.IDB, date time.Time) error {
-	// https://stackoverflow.com/questions/48243934/mocking-postgresql-now-function-for-testing
-	_, err := db.ExecContext(context.Background(), setFakeNowFn, date)
-	if err != nil {
-		return fmt.Errorf("exec query: %w", err)
+// RestoreTime removes the overrides of FreezeTime, and returns the connection it reserved, if any, to the pool.
+func RestoreTime(clock *Clock) error {
+	if clock.conn != nil {
+		return clock.conn.Close()
 	}
 
-	return nil
+	return clock.Restore()
 }
 
-func RestoreTime(db bun.IDB) error {
-	_, err := db.ExecContext(context.Background(), unsetFakeNowFn)
+// FreezeTestTime freezes the database time, like FreezeTime, and restores it when the test completes. The test
+// fails immediately if the time cannot be frozen.
+//
+// Queries must be sent through the returned handle to see the frozen time.
+func FreezeTestTime(t testing.TB, db bun.IDB, date time.Time) bun.IDB {
+	t.Helper()
+
+	clock, handle, err := FreezeTime(db, date)
 	if err != nil {
-		return fmt.Errorf("exec query: %w", err)
+		t.Fatalf("freeze time: %v", err)
 	}
 
-	return nil
+	t.Cleanup(func() {
+		if err := RestoreTime(clock); err != nil {
+			t.Errorf("restore time: %v", err)
+		}
+	})
+
+	return handle
 }
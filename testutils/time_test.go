@@ -25,15 +25,41 @@ func TestFreezeTime(t *testing.T) {
 
 	t.Log("FreezeTime")
 
-	require.NoError(t, asqltest.FreezeTime(db, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)))
-	require.NoError(t, db.NewSelect().ColumnExpr("NOW()").Scan(context.Background(), &dbTime))
+	clock, frozen, err := asqltest.FreezeTime(db, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NoError(t, frozen.NewSelect().ColumnExpr("NOW()").Scan(context.Background(), &dbTime))
 
 	require.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), dbTime)
 
+	// The other connections of the pool are not affected.
+	require.NoError(t, db.NewSelect().ColumnExpr("NOW()").Scan(context.Background(), &dbTime))
+	require.True(t, now.Before(dbTime))
+
 	t.Log("UnfreezeTime")
 
-	require.NoError(t, asqltest.RestoreTime(db))
-	require.NoError(t, db.NewSelect().ColumnExpr("NOW()").Scan(context.Background(), &dbTime))
+	require.NoError(t, asqltest.RestoreTime(clock))
+
+	// The reserved connection went back to the pool without the overrides.
+	for range 5 {
+		require.NoError(t, db.NewSelect().ColumnExpr("NOW()").Scan(context.Background(), &dbTime))
+		require.True(t, now.Before(dbTime))
+	}
+}
 
+func TestFreezeTestTime(t *testing.T) {
+	db := asqltest.NewDB(t)
+
+	var dbTime time.Time
+
+	t.Run("Frozen", func(t *testing.T) {
+		frozen := asqltest.FreezeTestTime(t, db, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		require.NoError(t, frozen.NewSelect().ColumnExpr("NOW()").Scan(context.Background(), &dbTime))
+		require.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), dbTime)
+	})
+
+	// Time has been restored on cleanup.
+	now := time.Now()
+	require.NoError(t, db.NewSelect().ColumnExpr("NOW()").Scan(context.Background(), &dbTime))
 	require.True(t, now.Before(dbTime))
 }
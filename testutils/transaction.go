@@ -2,21 +2,29 @@ package asqltest
 
 import (
 	"context"
+	"testing"
 
 	"github.com/uptrace/bun"
 )
 
+// BeginTestTX begins a transaction, and inserts the given fixtures in it. Use []any to insert fixtures of different
+// types, in dependency order. See InsertFixtures for details.
+//
+// It panics with a *FixtureError if a fixture cannot be inserted.
 func BeginTestTX[T any](database bun.IDB, fixtures []T) bun.Tx {
 	transaction, err := database.BeginTx(context.Background(), nil)
 	if err != nil {
 		panic(err)
 	}
 
-	for _, fixture := range fixtures {
-		_, err := transaction.NewInsert().Model(fixture).Exec(context.Background())
-		if err != nil {
-			panic(err)
-		}
+	models := make([]any, len(fixtures))
+	for i, fixture := range fixtures {
+		models[i] = fixture
+	}
+
+	if err = InsertFixtures(context.Background(), transaction, models...); err != nil {
+		RollbackTestTX(transaction)
+		panic(err)
 	}
 
 	return transaction
@@ -25,3 +33,58 @@ func BeginTestTX[T any](database bun.IDB, fixtures []T) bun.Tx {
 func RollbackTestTX(transaction bun.Tx) {
 	_ = transaction.Rollback()
 }
+
+// NewTx begins a transaction, and inserts the given fixtures in it, using InsertFixtures. The transaction is rolled
+// back when the test completes.
+//
+// Unlike BeginTestTX, the test fails immediately if the transaction cannot be set up.
+func NewTx(t testing.TB, database bun.IDB, fixtures ...any) bun.Tx {
+	t.Helper()
+
+	transaction, err := database.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("begin transaction: %v", err)
+	}
+
+	t.Cleanup(func() { RollbackTestTX(transaction) })
+
+	if err = InsertFixtures(context.Background(), transaction, fixtures...); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return transaction
+}
+
+// NewSavepoint opens a savepoint in the given transaction, and inserts the given fixtures in it, using
+// InsertFixtures. The transaction is rolled back to the savepoint when the test completes.
+//
+// This lets sub-tests share the fixtures of their parent transaction, while each starts from the same state:
+//
+//	tx := asqltest.NewTx(t, db, sharedFixtures...)
+//
+//	for _, testCase := range testCases {
+//		t.Run(testCase.name, func(t *testing.T) {
+//			tx := asqltest.NewSavepoint(t, tx, testCase.fixtures...)
+//			// ...
+//		})
+//	}
+//
+// Rolling back to a savepoint also recovers the transaction from failed statements, so a sub-test that expects
+// an error does not break the next ones. A transaction runs on a single connection: sub-tests sharing it must
+// not run in parallel.
+func NewSavepoint(t testing.TB, transaction bun.Tx, fixtures ...any) bun.Tx {
+	t.Helper()
+
+	savepoint, err := transaction.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("open savepoint: %v", err)
+	}
+
+	t.Cleanup(func() { RollbackTestTX(savepoint) })
+
+	if err = InsertFixtures(context.Background(), savepoint, fixtures...); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return savepoint
+}
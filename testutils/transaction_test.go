@@ -55,3 +55,58 @@ func TestTransaction(t *testing.T) {
 	// New model should not be available through db.
 	require.Error(t, db.NewSelect().Model(&databasemocks.Table1Model{}).Where("id = ?", 2).Scan(context.Background()))
 }
+
+func TestNewTx(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+
+	t.Run("Fixtures", func(t *testing.T) {
+		tx := asqltest.NewTx(t, db,
+			&databasemocks.Table1Model{ID: 1, Name: "foo"},
+			&databasemocks.Table2Model{ID: 1, Name: "bar"},
+		)
+
+		var model1 databasemocks.Table1Model
+		require.NoError(t, tx.NewSelect().Model(&model1).Where("id = ?", 1).Scan(context.Background()))
+		require.Equal(t, "foo", model1.Name)
+
+		var model2 databasemocks.Table2Model
+		require.NoError(t, tx.NewSelect().Model(&model2).Where("id = ?", 1).Scan(context.Background()))
+		require.Equal(t, "bar", model2.Name)
+	})
+
+	// The transaction of the previous test has been rolled back on cleanup.
+	require.Error(t, db.NewSelect().Model(&databasemocks.Table1Model{}).Where("id = ?", 1).Scan(context.Background()))
+}
+
+func TestNewSavepoint(t *testing.T) {
+	db := asqltest.NewDB(t, asqltest.WithMigrations(databasemocks.MigrationsAll))
+	tx := asqltest.NewTx(t, db, &databasemocks.Table1Model{ID: 1, Name: "shared"})
+
+	testCases := []struct {
+		name string
+		id   int64
+	}{
+		{name: "First", id: 2},
+		// Inserting the same ID again only works if the previous case was rolled back.
+		{name: "Second", id: 2},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			savepoint := asqltest.NewSavepoint(t, tx, &databasemocks.Table1Model{ID: testCase.id, Name: "case"})
+
+			count, err := savepoint.NewSelect().Model((*databasemocks.Table1Model)(nil)).Count(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 2, count)
+
+			// Failed statements are recovered by the rollback.
+			_, err = savepoint.NewInsert().Model(&databasemocks.Table1Model{ID: 1, Name: "duplicate"}).
+				Exec(context.Background())
+			require.Error(t, err)
+		})
+	}
+
+	count, err := tx.NewSelect().Model((*databasemocks.Table1Model)(nil)).Count(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}